@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"os"
+	"path"
+)
+
+func runDiff(args []string) {
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	diffCmd.Usage = func() {
+		fmt.Fprintln(diffCmd.Output(), "Usage of diff: kunitori diff [flags] old.json new.json")
+		diffCmd.PrintDefaults()
+	}
+	diffOut := diffCmd.String("out", ".", "out directory path")
+	diffFormat := diffCmd.String("format", "markdown", "output format (json: diff.json, markdown: diff.md)")
+	diffOutputFile := diffCmd.String(
+		"o",
+		"",
+		"write the output to the file instead of -out (- means stdout)",
+	)
+	diffVerbose := diffCmd.Bool("v", false, "show debug messages")
+	diffQuiet := diffCmd.Bool("q", false, "show warnings only")
+
+	err := diffCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if diffCmd.NArg() != 2 {
+		fmt.Println("should specify old and new generate result paths")
+		os.Exit(1)
+	}
+
+	if *diffFormat != pkg.FormatJson && *diffFormat != pkg.FormatMarkdown {
+		fmt.Println(fmt.Sprintf("invalid format: %v", *diffFormat))
+		os.Exit(1)
+	}
+
+	if *diffOutputFile == "" {
+		if _, err := os.Stat(*diffOut); os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	logger := newLogger(logWriterFor(*diffOutputFile), *diffVerbose, *diffQuiet)
+
+	oldResult := loadGenerateResult(diffCmd.Arg(0))
+	newResult := loadGenerateResult(diffCmd.Arg(1))
+
+	diff, err := pkg.DiffGenerateResults(oldResult, newResult)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	fileName := ""
+	switch *diffFormat {
+	case pkg.FormatJson:
+		data, err = json.Marshal(diff)
+		fileName = "diff.json"
+	case pkg.FormatMarkdown:
+		var markdown string
+		markdown, err = pkg.RenderResultDiffMarkdown(diff)
+		data = []byte(markdown)
+		fileName = "diff.md"
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *diffOutputFile == "-" {
+		_, err := os.Stdout.Write(data)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	} else if *diffOutputFile != "" {
+		fileName = *diffOutputFile
+	} else {
+		fileName = path.Join(*diffOut, fileName)
+	}
+
+	absFileName := writeOutput(fileName, data)
+	logger.Infof("output: %v", absFileName)
+}
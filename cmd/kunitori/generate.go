@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func runGenerate(args []string) {
+	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
+	generateOut := generateCmd.String("out", ".", "out directory path")
+	generateJson := generateCmd.Bool("json", false, "export as json format (same as -format json)")
+	var generateFormats arrayFlags
+	generateCmd.Var(
+		&generateFormats,
+		"format",
+		"output format (multiple specified or comma separated, html: chart.html, json: generate.json, csv: authors.csv and areas.csv, markdown: kunitori.md, svg: chart.svg) (default html)",
+	)
+	generateNameTemplate := generateCmd.String(
+		"name",
+		pkg.DefaultOutputNameTemplate,
+		"output file name template (fields: .Name, .Ext, .Format, .Repository, .Hash, .Date)",
+	)
+	generateTemplate := generateCmd.String(
+		"template",
+		"",
+		"html/template file rendering chart.html instead of the embedded one (executed with the generate result)",
+	)
+	generateOutputFile := generateCmd.String(
+		"o",
+		"",
+		"write the single output to the file instead of -out (- means stdout)",
+	)
+	generateUrl := generateCmd.String("url", "", "repository url")
+	generatePath := generateCmd.String("path", "", "repository path")
+	generateRegion := generateCmd.String("region", "JP", "chart region")
+	generateAllocation := generateCmd.String(
+		"allocation",
+		pkg.AllocationGreedy,
+		fmt.Sprintf(
+			"area allocation strategy (%v)",
+			strings.Join([]string{
+				pkg.AllocationGreedy,
+				pkg.AllocationLargestRemainder,
+				pkg.AllocationDHondt,
+				pkg.AllocationContiguous,
+				pkg.AllocationStable,
+			}, ", "),
+		),
+	)
+	generateGroupBy := generateCmd.String(
+		"group-by",
+		pkg.GroupByAuthor,
+		fmt.Sprintf("allocate areas to (%v, %v)", pkg.GroupByAuthor, pkg.GroupByTeam),
+	)
+	generateTeams := generateCmd.String(
+		"teams",
+		"",
+		"team definition file path (format: json object of team name to member emails)",
+	)
+	generateOwnership := generateCmd.String(
+		"ownership",
+		pkg.OwnershipBlame,
+		fmt.Sprintf(
+			"attribute lines by (%v: authors of lines, %v: owners declared in CODEOWNERS)",
+			pkg.OwnershipBlame,
+			pkg.OwnershipCodeOwners,
+		),
+	)
+	generateOwnershipDiff := generateCmd.Bool(
+		"ownership-diff",
+		false,
+		"compare CODEOWNERS with authors of lines (requires -ownership codeowners)",
+	)
+	generateHalfLife := generateCmd.Duration(
+		"half-life",
+		0,
+		"weight lines by recency with the half-life (0 means no weighting)",
+	)
+	generatePalette := generateCmd.String(
+		"palette",
+		pkg.PaletteRank,
+		fmt.Sprintf(
+			"colour palette of authors (%v: distinct colours, %v: colour-blind safe colours, %v: gradient by rank)",
+			pkg.PaletteCategorical,
+			pkg.PaletteColorBlind,
+			pkg.PaletteRank,
+		),
+	)
+	generateColors := generateCmd.String(
+		"colors",
+		"",
+		"author colour file path (format: json object of author email or team name to #rrggbb)",
+	)
+	generateLang := generateCmd.String(
+		"lang",
+		pkg.LangEn,
+		fmt.Sprintf("language of headings, numbers and area names of the chart (%v, %v)", pkg.LangEn, pkg.LangJa),
+	)
+	generateDetails := generateCmd.Bool(
+		"details",
+		false,
+		"add lines of each file and directory by author to the result",
+	)
+	generateSurvival := generateCmd.Bool(
+		"survival",
+		false,
+		"count how many lines added between the picked commits survive at the later ones",
+	)
+	generateSince := generateCmd.String(
+		"since",
+		"",
+		fmt.Sprintf("filter commit since date (format: %v)", time.RFC3339),
+	)
+	generateUntil := generateCmd.String(
+		"until",
+		"",
+		fmt.Sprintf("filter commit until date (format: %v)", time.RFC3339),
+	)
+	generateInterval := generateCmd.Duration(
+		"interval",
+		time.Hour*24*30,
+		"commit pick interval",
+	)
+	generateLimit := generateCmd.Int(
+		"limit",
+		12,
+		"commit pick limit",
+	)
+
+	generateTimeout := generateCmd.Duration(
+		"timeout",
+		0,
+		"stop generating after the duration (0 means no timeout)",
+	)
+	generatePartial := generateCmd.Bool(
+		"partial",
+		false,
+		"write results of counted commits when interrupted or timed out",
+	)
+	generateVerbose := generateCmd.Bool("v", false, "show debug messages")
+	generateQuiet := generateCmd.Bool("q", false, "show warnings only")
+	generateProgress := generateCmd.String(
+		"progress",
+		"text",
+		"progress output (text: stdout, json: newline-delimited json events on stderr, none)",
+	)
+	generateLoginCache := generateCmd.String("login-cache", "", "github login cache file path")
+	generateLoginCacheTtl := generateCmd.Duration(
+		"login-cache-ttl",
+		time.Hour*24*7,
+		"how long emails without github login are kept in login cache",
+	)
+	generateLoginOverrides := generateCmd.String(
+		"login-overrides",
+		"",
+		"github login overrides file path (format: email=login per line)",
+	)
+
+	var filters arrayFlags
+	generateCmd.Var(
+		&filters,
+		"filters",
+		"target file filter regex (multiple specified)",
+	)
+
+	var authors arrayFlags
+	generateCmd.Var(
+		&authors,
+		"authors",
+		"target file author regex (multiple specified, format: author=regex)",
+	)
+
+	err := generateCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	since, until := time.UnixMilli(0).UTC(), time.Now().UTC()
+	if *generateSince != "" {
+		since, err = time.Parse(time.RFC3339, *generateSince)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *generateUntil != "" {
+		until, err = time.Parse(time.RFC3339, *generateUntil)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	filterRegexes := parseFilters(filters)
+	authorRegexes := parseAuthors(authors)
+
+	if *generateJson {
+		generateFormats = append(generateFormats, pkg.FormatJson)
+	}
+	if len(generateFormats) == 0 {
+		generateFormats = append(generateFormats, pkg.FormatHtml)
+	}
+	formats, nameTemplate := parseOutputFlags(generateFormats, *generateNameTemplate, *generateOut, *generateOutputFile)
+	renderOptions := newRenderOptions(*generateTemplate)
+
+	if *generateLoginOverrides != "" {
+		if _, err := os.Stat(*generateLoginOverrides); os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	logger := newLogger(logWriterFor(*generateOutputFile), *generateVerbose, *generateQuiet)
+
+	var progress func(event pkg.ProgressEvent)
+	switch *generateProgress {
+	case "text":
+		progress = func(event pkg.ProgressEvent) {
+			if event.Phase == pkg.ProgressPhaseCountLines && event.FileIndex == 0 {
+				logger.Infof(
+					"count lines: progress=%v/%v, hash=%v, when=%v",
+					event.CommitIndex,
+					event.CommitCount,
+					event.CommitHash,
+					event.CommittedAt.String(),
+				)
+			}
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stderr)
+		progress = func(event pkg.ProgressEvent) {
+			err := encoder.Encode(event)
+			if err != nil {
+				logger.Warnf("failed to write progress: err=%v", err)
+			}
+		}
+	case "none":
+	default:
+		fmt.Println(fmt.Sprintf("invalid progress: %v", *generateProgress))
+		os.Exit(1)
+	}
+
+	if _, err := pkg.GetAllocator(*generateAllocation); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if _, ok := pkg.Palettes[*generatePalette]; !ok {
+		fmt.Println(fmt.Sprintf("invalid palette: %v", *generatePalette))
+		os.Exit(1)
+	}
+
+	err = pkg.ValidateLang(*generateLang)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	colors := loadColors(*generateColors)
+	if *generatePalette == pkg.PaletteRank && colors != nil {
+		fmt.Println("should specify a palette other than rank to use colors")
+		os.Exit(1)
+	}
+
+	var teams pkg.Teams
+	if *generateTeams != "" {
+		teams, err = pkg.LoadTeams(*generateTeams)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	switch *generateGroupBy {
+	case pkg.GroupByAuthor:
+	case pkg.GroupByTeam:
+		if teams == nil {
+			fmt.Println("should specify teams to group by team")
+			os.Exit(1)
+		}
+	default:
+		fmt.Println(fmt.Sprintf("invalid group by: %v", *generateGroupBy))
+		os.Exit(1)
+	}
+
+	switch *generateOwnership {
+	case pkg.OwnershipBlame:
+		if *generateOwnershipDiff {
+			fmt.Println("should specify -ownership codeowners to diff ownership")
+			os.Exit(1)
+		}
+	case pkg.OwnershipCodeOwners:
+		if *generateHalfLife > 0 {
+			fmt.Println("should specify -ownership blame to weight lines by recency")
+			os.Exit(1)
+		}
+		if *generateSurvival {
+			fmt.Println("should specify -ownership blame to count survival")
+			os.Exit(1)
+		}
+	default:
+		fmt.Println(fmt.Sprintf("invalid ownership: %v", *generateOwnership))
+		os.Exit(1)
+	}
+
+	if *generateUrl == "" && *generatePath == "" {
+		fmt.Println("should specify repository url or path")
+		os.Exit(1)
+	}
+
+	if *generateUrl != "" {
+		if _, err := url.ParseRequestURI(*generateUrl); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *generatePath != "" {
+		if _, err := os.Stat(*generatePath); os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	options := pkg.GenerateOptions{
+		RepositoryUrl:  *generateUrl,
+		RepositoryPath: *generatePath,
+		Region:         *generateRegion,
+		Allocation:     *generateAllocation,
+		SearchCommitsOptions: &pkg.SearchCommitsOptions{
+			Since:    since,
+			Until:    until,
+			Interval: *generateInterval,
+			Limit:    *generateLimit,
+		},
+		CountLinesOption: &pkg.CountLinesOption{
+			Filters:       filterRegexes,
+			AuthorRegexes: authorRegexes,
+			HalfLife:      *generateHalfLife,
+		},
+		LoginOptions: &pkg.LoginOptions{
+			CachePath:        *generateLoginCache,
+			NegativeCacheTTL: *generateLoginCacheTtl,
+			OverridesPath:    *generateLoginOverrides,
+		},
+		GroupBy:       *generateGroupBy,
+		Teams:         teams,
+		Ownership:     *generateOwnership,
+		OwnershipDiff: *generateOwnershipDiff,
+		Details:       *generateDetails,
+		Survival:      *generateSurvival,
+		Palette:       *generatePalette,
+		Lang:          *generateLang,
+		Colors:        colors,
+		Progress:      progress,
+		Logger:        logger,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *generateTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *generateTimeout)
+		defer cancel()
+	}
+
+	exitCode := 0
+	generateResult, err := pkg.Generate(ctx, &options)
+	stop()
+	if err != nil {
+		if generateResult == nil || !*generatePartial {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		logger.Warnf("write partial result: commits=%v, err=%v", len(generateResult.Commits), err)
+		exitCode = 1
+	}
+
+	outputs, err := pkg.RenderOutputs(generateResult, formats, renderOptions)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	writeOutputs(outputs, generateResult, *generateOut, nameTemplate, *generateOutputFile, logger)
+
+	os.Exit(exitCode)
+}
@@ -1,19 +1,15 @@
 package main
 
 import (
-	"encoding/json"
-	"flag"
 	"fmt"
 	"github.com/dlclark/regexp2"
 	"github.com/yktakaha4/kunitori/pkg"
 	"io"
-	"log"
-	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
+	"text/template"
 )
 
 var (
@@ -32,207 +28,256 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
-func main() {
-	if os.Getenv("DEBUG") == "" {
-		log.SetOutput(io.Discard)
+// parseFilters compiles filter regexes. Every file is matched if no filter is given.
+func parseFilters(filters arrayFlags) []*regexp2.Regexp {
+	filterRegexes := make([]*regexp2.Regexp, 0)
+	for _, filter := range filters {
+		regex, err := regexp2.Compile(filter, 0)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		filterRegexes = append(filterRegexes, regex)
 	}
 
-	defaultHelpMessage := fmt.Sprintf(`Kunitori (国盗り)
-
-Version: %v
-Commit: %v
+	if len(filterRegexes) == 0 {
+		filterRegexes = append(filterRegexes, regexp2.MustCompile(".+", 0))
+	}
 
-SubCommands:
-	generate	...	generate Kunitori chart
-`, Version, ShortCommit)
+	return filterRegexes
+}
 
-	if len(os.Args) < 2 {
-		fmt.Print(defaultHelpMessage)
-		os.Exit(1)
-	}
+// parseAuthors compiles author regexes given in author=regex format.
+func parseAuthors(authors arrayFlags) []pkg.AuthorRegex {
+	authorRegexes := make([]pkg.AuthorRegex, 0)
+	for _, author := range authors {
+		parts := strings.Split(author, "=")
+		if len(parts) != 2 {
+			fmt.Println(fmt.Sprintf("invalid format: %v", authors))
+			os.Exit(1)
+		}
 
-	switch os.Args[1] {
-	case "generate":
-		generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
-		generateOut := generateCmd.String("out", ".", "out directory path")
-		generateJson := generateCmd.Bool("json", false, "export as json format")
-		generateUrl := generateCmd.String("url", "", "repository url")
-		generatePath := generateCmd.String("path", "", "repository path")
-		generateRegion := generateCmd.String("region", "JP", "chart region")
-		generateSince := generateCmd.String(
-			"since",
-			"",
-			fmt.Sprintf("filter commit since date (format: %v)", time.RFC3339),
-		)
-		generateUntil := generateCmd.String(
-			"until",
-			"",
-			fmt.Sprintf("filter commit until date (format: %v)", time.RFC3339),
-		)
-		generateInterval := generateCmd.Duration(
-			"interval",
-			time.Hour*24*30,
-			"commit pick interval",
-		)
-		generateLimit := generateCmd.Int(
-			"limit",
-			12,
-			"commit pick limit",
-		)
-
-		var filters arrayFlags
-		generateCmd.Var(
-			&filters,
-			"filters",
-			"target file filter regex (multiple specified)",
-		)
-
-		var authors arrayFlags
-		generateCmd.Var(
-			&authors,
-			"authors",
-			"target file author regex (multiple specified, format: author=regex)",
-		)
-
-		err := generateCmd.Parse(os.Args[2:])
+		regex, err := regexp2.Compile(parts[1], 0)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		authorRegexes = append(authorRegexes, pkg.AuthorRegex{
+			Condition: regex,
+			Author:    parts[0],
+		})
+	}
 
-		since, until := time.UnixMilli(0).UTC(), time.Now().UTC()
-		if *generateSince != "" {
-			since, err = time.Parse(time.RFC3339, *generateSince)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-		}
-		if *generateUntil != "" {
-			until, err = time.Parse(time.RFC3339, *generateUntil)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-		}
+	return authorRegexes
+}
 
-		filterRegexes := make([]*regexp2.Regexp, 0)
-		for _, filter := range filters {
-			regex, err := regexp2.Compile(filter, 0)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			filterRegexes = append(filterRegexes, regex)
-		}
+func newLogger(w io.Writer, verbose bool, quiet bool) pkg.Logger {
+	logLevel := pkg.LogLevelInfo
+	if verbose || os.Getenv("DEBUG") != "" {
+		logLevel = pkg.LogLevelDebug
+	} else if quiet {
+		logLevel = pkg.LogLevelWarn
+	}
+	return pkg.NewWriterLogger(w, logLevel)
+}
 
-		if len(filterRegexes) == 0 {
-			filterRegexes = append(filterRegexes, regexp2.MustCompile(".+", 0))
-		}
+// logWriterFor moves messages to stderr while outputs are written to stdout.
+func logWriterFor(outputFile string) io.Writer {
+	if outputFile == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
 
-		authorRegexes := make([]pkg.AuthorRegex, 0)
-		for _, author := range authors {
-			parts := strings.Split(author, "=")
-			if len(parts) != 2 {
-				fmt.Println(fmt.Sprintf("invalid format: %v", authors))
-				os.Exit(1)
-			}
+// parseOutputFlags validates -format, -name, -out and -o flags shared by subcommands writing GenerateResult.
+func parseOutputFlags(formatFlags arrayFlags, nameTemplateText string, outDir string, outputFile string) ([]string, *template.Template) {
+	formats, err := pkg.SplitFormats(formatFlags)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-			regex, err := regexp2.Compile(parts[1], 0)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			authorRegexes = append(authorRegexes, pkg.AuthorRegex{
-				Condition: regex,
-				Author:    parts[0],
-			})
-		}
+	nameTemplate, err := pkg.ParseOutputNameTemplate(nameTemplateText)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-		if _, err := os.Stat(*generateOut); os.IsNotExist(err) {
-			fmt.Println(err)
+	if outputFile != "" {
+		if len(formats) != 1 || formats[0] == pkg.FormatCsv {
+			fmt.Println("should specify a single format which writes a single file to use -o")
 			os.Exit(1)
 		}
+	} else if _, err := os.Stat(outDir); os.IsNotExist(err) {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return formats, nameTemplate
+}
 
-		if *generateUrl == "" && *generatePath == "" {
-			fmt.Println("should specify repository url or path")
+// newRenderOptions reads the chart template at templatePath. The embedded chart.html is used if it is empty.
+func newRenderOptions(templatePath string) *pkg.RenderOptions {
+	options := &pkg.RenderOptions{}
+	if templatePath != "" {
+		text, err := os.ReadFile(templatePath)
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
-
-		if *generateUrl != "" {
-			if _, err := url.ParseRequestURI(*generateUrl); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+		options.ChartTemplate, err = pkg.NewChartTemplate(string(text))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
+	}
+	return options
+}
 
-		if *generatePath != "" {
-			if _, err := os.Stat(*generatePath); os.IsNotExist(err) {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-		}
+// colorAuthors colours the authors of generateResult again if palette or colorsPath is given.
+// The palette of the result is kept if palette is empty.
+func colorAuthors(generateResult *pkg.GenerateResult, palette string, colorsPath string) {
+	if palette == "" && colorsPath == "" {
+		return
+	}
 
-		options := pkg.GenerateOptions{
-			RepositoryUrl:  *generateUrl,
-			RepositoryPath: *generatePath,
-			Region:         *generateRegion,
-			SearchCommitsOptions: &pkg.SearchCommitsOptions{
-				Since:    since,
-				Until:    until,
-				Interval: *generateInterval,
-				Limit:    *generateLimit,
-			},
-			CountLinesOption: &pkg.CountLinesOption{
-				Filters:       filterRegexes,
-				AuthorRegexes: authorRegexes,
-			},
+	if palette == "" {
+		palette = generateResult.Palette
+		if palette == "" || palette == pkg.PaletteRank {
+			palette = pkg.PaletteCategorical
 		}
+	}
 
-		generateResult, err := pkg.Generate(&options)
-		if err != nil {
-			panic(err)
-		}
+	err := pkg.ColorAuthors(generateResult, palette, loadColors(colorsPath))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
 
-		var fileName string
-		var data []byte
+// loadColors reads colours of authors from colorsPath, or returns nil if it is empty.
+func loadColors(colorsPath string) map[string]string {
+	if colorsPath == "" {
+		return nil
+	}
+	colors, err := pkg.LoadColors(colorsPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return colors
+}
 
-		if *generateJson {
-			fileName = path.Join(*generateOut, "generate.json")
-			data, err = json.Marshal(generateResult)
+// writeOutputs writes outputs into outDir named by nameTemplate, or the single output to outputFile if it is set.
+func writeOutputs(outputs []pkg.Output, generateResult *pkg.GenerateResult, outDir string, nameTemplate *template.Template, outputFile string, logger pkg.Logger) {
+	if outputFile == "-" {
+		for _, output := range outputs {
+			_, err := os.Stdout.Write(output.Data)
 			if err != nil {
-				fmt.Println(err)
+				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
 			}
-		} else {
-			fileName = path.Join(*generateOut, "chart.html")
-			html, err := pkg.RenderChartHtml(generateResult)
-			if err != nil {
-				panic(err)
-			}
-			data = []byte(html)
 		}
-
-		absFileName, err := filepath.Abs(fileName)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		return
+	} else if outputFile != "" {
+		for _, output := range outputs {
+			absFileName := writeOutput(outputFile, output.Data)
+			logger.Infof("output: %v", absFileName)
 		}
+		return
+	}
 
-		f, err := os.Create(absFileName)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	fileNames, err := pkg.OutputFileNames(nameTemplate, outputs, generateResult)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for i, output := range outputs {
+		absFileName := writeOutput(path.Join(outDir, fileNames[i]), output.Data)
+		logger.Infof("output: %v", absFileName)
+	}
+}
 
-		_, err = f.Write(data)
+// loadGenerateResult reads generate.json from fileName, or stdin if it is "-".
+func loadGenerateResult(fileName string) *pkg.GenerateResult {
+	var reader io.Reader = os.Stdin
+	if fileName != "-" {
+		f, err := os.Open(fileName)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		defer f.Close()
+		reader = f
+	}
 
-		fmt.Printf("output: %v", absFileName)
-		os.Exit(0)
+	generateResult, err := pkg.LoadGenerateResult(reader)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("%v: %v", fileName, err))
+		os.Exit(1)
+	}
+
+	return generateResult
+}
+
+// writeOutput writes data to fileName and returns its absolute path.
+func writeOutput(fileName string, data []byte) string {
+	absFileName, err := filepath.Abs(fileName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	f, err := os.Create(absFileName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return absFileName
+}
+
+func main() {
+	defaultHelpMessage := fmt.Sprintf(`Kunitori (国盗り)
+
+Version: %v
+Commit: %v
+
+SubCommands:
+	generate	...	generate Kunitori chart
+	render		...	render Kunitori chart from generate.json
+	merge		...	merge generate.json of shards or repositories
+	diff		...	compare rankings and areas of two generate.json
+	validate	...	validate generate.json against its json schema
+	report		...	report risks of ownership (busfactor)
+`, Version, ShortCommit)
+
+	if len(os.Args) < 2 {
+		fmt.Print(defaultHelpMessage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "generate":
+		runGenerate(os.Args[2:])
+	case "render":
+		runRender(os.Args[2:])
+	case "merge":
+		runMerge(os.Args[2:])
+	case "diff":
+		runDiff(os.Args[2:])
+	case "validate":
+		runValidate(os.Args[2:])
+	case "report":
+		runReport(os.Args[2:])
 	default:
 		fmt.Print(defaultHelpMessage)
 		os.Exit(1)
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"os"
+)
+
+func runMerge(args []string) {
+	mergeCmd := flag.NewFlagSet("merge", flag.ExitOnError)
+	mergeCmd.Usage = func() {
+		fmt.Fprintln(mergeCmd.Output(), "Usage of merge: kunitori merge [flags] generate.json generate.json...")
+		mergeCmd.PrintDefaults()
+	}
+	mergeOut := mergeCmd.String("out", ".", "out directory path")
+	var mergeFormats arrayFlags
+	mergeCmd.Var(
+		&mergeFormats,
+		"format",
+		"output format (multiple specified or comma separated, html: chart.html, json: generate.json, csv: authors.csv and areas.csv, markdown: kunitori.md, svg: chart.svg) (default json)",
+	)
+	mergeNameTemplate := mergeCmd.String(
+		"name",
+		pkg.DefaultOutputNameTemplate,
+		"output file name template (fields: .Name, .Ext, .Format, .Repository, .Hash, .Date)",
+	)
+	mergeTemplate := mergeCmd.String(
+		"template",
+		"",
+		"html/template file rendering chart.html instead of the embedded one (executed with the generate result)",
+	)
+	mergeOutputFile := mergeCmd.String(
+		"o",
+		"",
+		"write the single output to the file instead of -out (- means stdout)",
+	)
+	mergePalette := mergeCmd.String(
+		"palette",
+		"",
+		fmt.Sprintf(
+			"colour authors again with the palette (%v, %v, %v) (default palette of the result)",
+			pkg.PaletteCategorical,
+			pkg.PaletteColorBlind,
+			pkg.PaletteRank,
+		),
+	)
+	mergeColors := mergeCmd.String(
+		"colors",
+		"",
+		"author colour file path (format: json object of author email or team name to #rrggbb)",
+	)
+	mergeVerbose := mergeCmd.Bool("v", false, "show debug messages")
+	mergeQuiet := mergeCmd.Bool("q", false, "show warnings only")
+
+	err := mergeCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if mergeCmd.NArg() < 2 {
+		fmt.Println("should specify two or more generate result paths")
+		os.Exit(1)
+	}
+
+	if len(mergeFormats) == 0 {
+		mergeFormats = append(mergeFormats, pkg.FormatJson)
+	}
+	formats, nameTemplate := parseOutputFlags(mergeFormats, *mergeNameTemplate, *mergeOut, *mergeOutputFile)
+	renderOptions := newRenderOptions(*mergeTemplate)
+
+	logger := newLogger(logWriterFor(*mergeOutputFile), *mergeVerbose, *mergeQuiet)
+
+	generateResults := make([]*pkg.GenerateResult, 0, mergeCmd.NArg())
+	for _, fileName := range mergeCmd.Args() {
+		generateResults = append(generateResults, loadGenerateResult(fileName))
+	}
+
+	ctx := pkg.WithLogger(context.Background(), logger)
+	generateResult, err := pkg.MergeGenerateResults(ctx, generateResults)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	colorAuthors(generateResult, *mergePalette, *mergeColors)
+
+	outputs, err := pkg.RenderOutputs(generateResult, formats, renderOptions)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	writeOutputs(outputs, generateResult, *mergeOut, nameTemplate, *mergeOutputFile, logger)
+}
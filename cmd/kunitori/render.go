@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"os"
+	"strings"
+)
+
+func runRender(args []string) {
+	renderCmd := flag.NewFlagSet("render", flag.ExitOnError)
+	renderIn := renderCmd.String("in", "", "generate.json path written by generate -format json (- means stdin)")
+	renderOut := renderCmd.String("out", ".", "out directory path")
+	var renderFormats arrayFlags
+	renderCmd.Var(
+		&renderFormats,
+		"format",
+		"output format (multiple specified or comma separated, html: chart.html, json: generate.json, csv: authors.csv and areas.csv, markdown: kunitori.md, svg: chart.svg) (default html)",
+	)
+	renderNameTemplate := renderCmd.String(
+		"name",
+		pkg.DefaultOutputNameTemplate,
+		"output file name template (fields: .Name, .Ext, .Format, .Repository, .Hash, .Date)",
+	)
+	renderTemplate := renderCmd.String(
+		"template",
+		"",
+		"html/template file rendering chart.html instead of the embedded one (executed with the generate result)",
+	)
+	renderOutputFile := renderCmd.String(
+		"o",
+		"",
+		"write the single output to the file instead of -out (- means stdout)",
+	)
+	renderRegion := renderCmd.String("region", "", "allocate areas of the region again (default region of the result)")
+	renderAllocation := renderCmd.String(
+		"allocation",
+		"",
+		fmt.Sprintf(
+			"allocate areas again with the strategy (%v) (default allocation of the result)",
+			strings.Join([]string{
+				pkg.AllocationGreedy,
+				pkg.AllocationLargestRemainder,
+				pkg.AllocationDHondt,
+				pkg.AllocationContiguous,
+				pkg.AllocationStable,
+			}, ", "),
+		),
+	)
+	renderPalette := renderCmd.String(
+		"palette",
+		"",
+		fmt.Sprintf(
+			"colour authors again with the palette (%v, %v, %v) (default palette of the result)",
+			pkg.PaletteCategorical,
+			pkg.PaletteColorBlind,
+			pkg.PaletteRank,
+		),
+	)
+	renderColors := renderCmd.String(
+		"colors",
+		"",
+		"author colour file path (format: json object of author email or team name to #rrggbb)",
+	)
+	renderLang := renderCmd.String(
+		"lang",
+		"",
+		fmt.Sprintf("language of headings, numbers and area names of the chart (%v, %v) (default language of the result)", pkg.LangEn, pkg.LangJa),
+	)
+	renderVerbose := renderCmd.Bool("v", false, "show debug messages")
+	renderQuiet := renderCmd.Bool("q", false, "show warnings only")
+
+	err := renderCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *renderIn == "" {
+		fmt.Println("should specify generate result path")
+		os.Exit(1)
+	}
+
+	if len(renderFormats) == 0 {
+		renderFormats = append(renderFormats, pkg.FormatHtml)
+	}
+	formats, nameTemplate := parseOutputFlags(renderFormats, *renderNameTemplate, *renderOut, *renderOutputFile)
+	renderOptions := newRenderOptions(*renderTemplate)
+
+	if *renderAllocation != "" {
+		if _, err := pkg.GetAllocator(*renderAllocation); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	logger := newLogger(logWriterFor(*renderOutputFile), *renderVerbose, *renderQuiet)
+
+	generateResult := loadGenerateResult(*renderIn)
+
+	if (*renderRegion != "" && *renderRegion != generateResult.Region) ||
+		(*renderAllocation != "" && *renderAllocation != generateResult.Allocation) {
+		region, allocation := generateResult.Region, generateResult.Allocation
+		if *renderRegion != "" {
+			region = *renderRegion
+		}
+		if *renderAllocation != "" {
+			allocation = *renderAllocation
+		}
+
+		logger.Infof("reallocate: region=%v, allocation=%v", region, allocation)
+
+		ctx := pkg.WithLogger(context.Background(), logger)
+		generateResult, err = pkg.Reallocate(ctx, generateResult, region, allocation)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	colorAuthors(generateResult, *renderPalette, *renderColors)
+
+	if *renderLang != "" {
+		err = pkg.ValidateLang(*renderLang)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		generateResult.Lang = *renderLang
+	}
+
+	outputs, err := pkg.RenderOutputs(generateResult, formats, renderOptions)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	writeOutputs(outputs, generateResult, *renderOut, nameTemplate, *renderOutputFile, logger)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+)
+
+func runReport(args []string) {
+	reportHelpMessage := `Usage of report:
+	busfactor	...	report directories owned by few authors
+`
+
+	if len(args) < 1 {
+		fmt.Print(reportHelpMessage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "busfactor":
+		runReportBusFactor(args[1:])
+	default:
+		fmt.Print(reportHelpMessage)
+		os.Exit(1)
+	}
+}
+
+func runReportBusFactor(args []string) {
+	busFactorCmd := flag.NewFlagSet("report busfactor", flag.ExitOnError)
+	busFactorOut := busFactorCmd.String("out", ".", "out directory path")
+	busFactorFormat := busFactorCmd.String("format", "json", "output format (json, markdown)")
+	busFactorUrl := busFactorCmd.String("url", "", "repository url")
+	busFactorPath := busFactorCmd.String("path", "", "repository path")
+	busFactorDepth := busFactorCmd.Int("depth", 2, "deepest directory level to report (0 means the root only)")
+	busFactorThreshold := busFactorCmd.Float64(
+		"threshold",
+		pkg.BusFactorDefaultThreshold*100,
+		"percentage of lines one author owns above which a directory is at risk",
+	)
+	busFactorVerbose := busFactorCmd.Bool("v", false, "show debug messages")
+	busFactorQuiet := busFactorCmd.Bool("q", false, "show warnings only")
+
+	var filters arrayFlags
+	busFactorCmd.Var(
+		&filters,
+		"filters",
+		"target file filter regex (multiple specified)",
+	)
+
+	var authors arrayFlags
+	busFactorCmd.Var(
+		&authors,
+		"authors",
+		"target file author regex (multiple specified, format: author=regex)",
+	)
+
+	err := busFactorCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	filterRegexes := parseFilters(filters)
+	authorRegexes := parseAuthors(authors)
+
+	if _, err := os.Stat(*busFactorOut); os.IsNotExist(err) {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *busFactorFormat != "json" && *busFactorFormat != "markdown" {
+		fmt.Println(fmt.Sprintf("invalid format: %v", *busFactorFormat))
+		os.Exit(1)
+	}
+
+	if *busFactorUrl == "" && *busFactorPath == "" {
+		fmt.Println("should specify repository url or path")
+		os.Exit(1)
+	}
+
+	if *busFactorUrl != "" {
+		if _, err := url.ParseRequestURI(*busFactorUrl); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *busFactorPath != "" {
+		if _, err := os.Stat(*busFactorPath); os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	logger := newLogger(os.Stdout, *busFactorVerbose, *busFactorQuiet)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	report, err := pkg.ReportBusFactor(ctx, &pkg.BusFactorOptions{
+		RepositoryUrl:  *busFactorUrl,
+		RepositoryPath: *busFactorPath,
+		CountLinesOption: &pkg.CountLinesOption{
+			Filters:       filterRegexes,
+			AuthorRegexes: authorRegexes,
+		},
+		Depth:     *busFactorDepth,
+		Threshold: *busFactorThreshold / 100,
+		Logger:    logger,
+	})
+	stop()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var fileName string
+	var data []byte
+
+	if *busFactorFormat == "markdown" {
+		fileName = path.Join(*busFactorOut, "busfactor.md")
+		markdown, err := pkg.RenderBusFactorMarkdown(report)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		data = []byte(markdown)
+	} else {
+		fileName = path.Join(*busFactorOut, "busfactor.json")
+		data, err = json.Marshal(report)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	absFileName := writeOutput(fileName, data)
+
+	logger.Infof("output: %v", absFileName)
+}
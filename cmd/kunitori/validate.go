@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/yktakaha4/kunitori/pkg"
+	"io"
+	"os"
+)
+
+func runValidate(args []string) {
+	validateCmd := flag.NewFlagSet("validate", flag.ExitOnError)
+	validateIn := validateCmd.String("in", "", "generate.json path to validate (- means stdin)")
+	validateSchema := validateCmd.Bool("schema", false, "print the json schema of generate.json instead of validating")
+
+	err := validateCmd.Parse(args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *validateSchema {
+		_, err := os.Stdout.Write(pkg.GenerateResultSchemaJson)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *validateIn == "" {
+		fmt.Println("should specify generate result path")
+		os.Exit(1)
+	}
+
+	var reader io.Reader = os.Stdin
+	if *validateIn != "-" {
+		f, err := os.Open(*validateIn)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	violations, err := pkg.ValidateGenerateResult(reader)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			fmt.Println(violation)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println(fmt.Sprintf("valid: schemaVersion=%v", pkg.GenerateResultSchemaVersion))
+}
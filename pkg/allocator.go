@@ -0,0 +1,594 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Allocator decides which author owns each area of the region from the line counts.
+type Allocator interface {
+	Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error)
+}
+
+const (
+	AllocationGreedy           = "greedy"
+	AllocationLargestRemainder = "largest-remainder"
+	AllocationDHondt           = "dhondt"
+	AllocationContiguous       = "contiguous"
+	AllocationStable           = "stable"
+)
+
+// GetAllocator returns a new Allocator of the strategy. An empty name means greedy.
+func GetAllocator(name string) (Allocator, error) {
+	switch name {
+	case "", AllocationGreedy:
+		return &greedyAllocator{}, nil
+	case AllocationLargestRemainder:
+		return &largestRemainderAllocator{}, nil
+	case AllocationDHondt:
+		return &dHondtAllocator{}, nil
+	case AllocationContiguous:
+		return &contiguousAllocator{}, nil
+	case AllocationStable:
+		return newStableAllocator(&contiguousAllocator{}), nil
+	}
+
+	return nil, fmt.Errorf("not found: allocation=%v", name)
+}
+
+// greedyAllocator walks areas in table order, and gives each area to the first ranked author with room left.
+type greedyAllocator struct{}
+
+func (a *greedyAllocator) Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	return AllocateAreas(ctx, areaInfo, result)
+}
+
+// largestRemainderAllocator apportions areas by the largest remainder (Hamilton) method weighted by area sizes.
+// Each author has the quota of its share of the total size. Areas from the largest first go to the author with
+// the most room whose quota still holds the whole area, which gives the integer parts of the quotas.
+// The areas left over go, from the largest, to the authors by descending remainder of their quotas, one for each author
+// until all authors have got one. With areas of the same size, it is exactly the largest remainder method.
+type largestRemainderAllocator struct{}
+
+func (a *largestRemainderAllocator) Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	logger := LoggerFromContext(ctx)
+
+	shares := rankAuthorShares(result)
+	if len(shares) == 0 || len(areaInfo.Areas) == 0 {
+		return []*AreaAuthor{}, nil
+	}
+
+	totalAreaSize := totalAreaSizeOf(areaInfo)
+	remainders := make([]float64, len(shares))
+	for index, share := range shares {
+		remainders[index] = share.linesRatio * totalAreaSize
+	}
+
+	areas := make([]Area, len(areaInfo.Areas))
+	copy(areas, areaInfo.Areas)
+	sort.SliceStable(areas, func(i, j int) bool {
+		return areas[i].Size > areas[j].Size
+	})
+
+	owners := map[string]string{}
+	assign := func(area Area, index int, phase string) {
+		logger.Debugf(
+			"allocate: phase=%v, area=%v, size=%v => author=%v, linesRatio=%.2f, remainder=%.2f",
+			phase,
+			area.Name,
+			area.Size,
+			shares[index].author,
+			shares[index].linesRatio,
+			remainders[index],
+		)
+		owners[area.Name] = shares[index].author
+		remainders[index] -= area.Size
+	}
+
+	leftovers := make([]Area, 0)
+	for _, area := range areas {
+		selected := -1
+		for index := range shares {
+			if remainders[index] >= area.Size && (selected == -1 || remainders[index] > remainders[selected]) {
+				selected = index
+			}
+		}
+		if selected == -1 {
+			leftovers = append(leftovers, area)
+			continue
+		}
+		assign(area, selected, "quota")
+	}
+
+	for len(leftovers) > 0 {
+		order := make([]int, len(shares))
+		for index := range order {
+			order[index] = index
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return remainders[order[i]] > remainders[order[j]]
+		})
+
+		for _, index := range order {
+			if len(leftovers) == 0 {
+				break
+			}
+			assign(leftovers[0], index, "remainder")
+			leftovers = leftovers[1:]
+		}
+	}
+
+	return newAreaAuthors(areaInfo, owners, shares), nil
+}
+
+// dHondtAllocator gives areas from the largest to the author with the highest D'Hondt quotient,
+// lines divided by the size the author would own with the area.
+type dHondtAllocator struct{}
+
+func (a *dHondtAllocator) Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	return allocateBySizeOrder(ctx, areaInfo, result, func(share authorShare, allocatedSize float64, area Area, totalAreaSize float64) float64 {
+		return share.lines / (allocatedSize + area.Size)
+	})
+}
+
+// contiguousAllocator grows the territory of each author, from the top ranked, as a connected region.
+// Each territory starts from the first unowned area in table order and takes the largest neighbouring areas
+// that fit the author's share. Areas left over go to the neighbouring owner furthest below its share.
+type contiguousAllocator struct{}
+
+func (a *contiguousAllocator) Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	logger := LoggerFromContext(ctx)
+
+	if len(areaInfo.Adjacency) == 0 {
+		logger.Debugf("no adjacency, fall back to largest remainder: region=%v", areaInfo.Region)
+		return (&largestRemainderAllocator{}).Allocate(ctx, areaInfo, result)
+	}
+
+	shares := rankAuthorShares(result)
+	if len(shares) == 0 || len(areaInfo.Areas) == 0 {
+		return []*AreaAuthor{}, nil
+	}
+
+	totalAreaSize := totalAreaSizeOf(areaInfo)
+	targetSizes := make([]float64, len(shares))
+	for index, share := range shares {
+		targetSizes[index] = share.linesRatio * totalAreaSize
+	}
+
+	allocation := newAreaAllocation(areaInfo, shares)
+	for index := range shares {
+		fits := func(area Area) bool {
+			return allocation.allocatedSizes[index]+area.Size <= targetSizes[index]+area.Size/2
+		}
+
+		seed := ""
+		for _, area := range areaInfo.Areas {
+			if _, ok := allocation.owners[area.Name]; ok || !fits(area) {
+				continue
+			}
+			// avoid starting in a pocket too small to grow into the share, unless there is no other choice
+			if seed == "" {
+				seed = area.Name
+			}
+			if allocation.unownedRegionSize(area.Name) >= targetSizes[index] {
+				seed = area.Name
+				break
+			}
+		}
+		if seed == "" {
+			continue
+		}
+		allocation.assign(seed, index)
+
+		for {
+			candidate := ""
+			for _, name := range allocation.frontier(index) {
+				area := allocation.areas[name]
+				if fits(area) && (candidate == "" || area.Size > allocation.areas[candidate].Size) {
+					candidate = name
+				}
+			}
+			if candidate == "" {
+				break
+			}
+			allocation.assign(candidate, index)
+		}
+
+		logger.Debugf(
+			"allocate: author=%v, linesRatio=%.2f, seed=%v, targetSize=%.0f, allocatedSize=%.0f",
+			shares[index].author,
+			shares[index].linesRatio,
+			seed,
+			targetSizes[index],
+			allocation.allocatedSizes[index],
+		)
+	}
+
+	allocation.fillLeftovers(targetSizes)
+
+	return newAreaAuthors(areaInfo, allocation.authorOwners(), shares), nil
+}
+
+// stableAllocator keeps authors in the areas they owned in the previous snapshot of the same filter,
+// and moves an area to another author only when it brings them closer to their shares.
+// The first snapshot starts from the areas given by the base allocator. It is stateful, so use a new one for each Generate.
+type stableAllocator struct {
+	base     Allocator
+	previous map[string]map[string]string
+}
+
+func newStableAllocator(base Allocator) *stableAllocator {
+	return &stableAllocator{
+		base:     base,
+		previous: map[string]map[string]string{},
+	}
+}
+
+func (a *stableAllocator) Allocate(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	logger := LoggerFromContext(ctx)
+
+	key := ""
+	if result.Filter != nil {
+		key = result.Filter.String()
+	}
+
+	previousOwners, ok := a.previous[key]
+	if !ok {
+		areaAuthors, err := a.base.Allocate(ctx, areaInfo, result)
+		if err != nil {
+			return nil, err
+		}
+
+		previousOwners = map[string]string{}
+		for _, areaAuthor := range areaAuthors {
+			previousOwners[areaAuthor.Area.Name] = areaAuthor.Author
+		}
+	}
+
+	shares := rankAuthorShares(result)
+	if len(shares) == 0 || len(areaInfo.Areas) == 0 {
+		return []*AreaAuthor{}, nil
+	}
+
+	totalAreaSize := totalAreaSizeOf(areaInfo)
+	targetSizes := make([]float64, len(shares))
+	for index, share := range shares {
+		targetSizes[index] = share.linesRatio * totalAreaSize
+	}
+
+	allocation := newAreaAllocation(areaInfo, shares)
+	for index, share := range shares {
+		for _, area := range areaInfo.Areas {
+			if previousOwners[area.Name] == share.author {
+				allocation.assign(area.Name, index)
+			}
+		}
+	}
+
+	// move one area at a time while it brings owners closer to their shares
+	for moves := 0; moves < len(areaInfo.Areas)*len(shares); moves++ {
+		if !allocation.moveBest(targetSizes) {
+			break
+		}
+	}
+
+	allocation.fillLeftovers(targetSizes)
+
+	owners := allocation.authorOwners()
+	changed := 0
+	for name, owner := range owners {
+		if previousOwners[name] != owner {
+			changed++
+		}
+	}
+	logger.Debugf("allocate stable: filter=%v, changed=%v/%v", key, changed, len(owners))
+
+	a.previous[key] = owners
+
+	return newAreaAuthors(areaInfo, owners, shares), nil
+}
+
+// areaAllocation tracks which author index owns each area while allocating.
+type areaAllocation struct {
+	areaInfo       *AreaInfo
+	shares         []authorShare
+	areas          map[string]Area
+	owners         map[string]int
+	allocatedSizes []float64
+}
+
+func newAreaAllocation(areaInfo *AreaInfo, shares []authorShare) *areaAllocation {
+	areas := map[string]Area{}
+	for _, area := range areaInfo.Areas {
+		areas[area.Name] = area
+	}
+
+	return &areaAllocation{
+		areaInfo:       areaInfo,
+		shares:         shares,
+		areas:          areas,
+		owners:         map[string]int{},
+		allocatedSizes: make([]float64, len(shares)),
+	}
+}
+
+func (a *areaAllocation) assign(name string, index int) {
+	if previous, ok := a.owners[name]; ok {
+		a.allocatedSizes[previous] -= a.areas[name].Size
+	}
+	a.owners[name] = index
+	a.allocatedSizes[index] += a.areas[name].Size
+}
+
+// unownedRegionSize returns the total size of unowned areas connected to the area through unowned areas.
+func (a *areaAllocation) unownedRegionSize(name string) float64 {
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	size := float64(0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		size += a.areas[current].Size
+
+		for _, neighbour := range a.areaInfo.Adjacency[current] {
+			if _, ok := a.owners[neighbour]; ok || visited[neighbour] {
+				continue
+			}
+			visited[neighbour] = true
+			queue = append(queue, neighbour)
+		}
+	}
+	return size
+}
+
+// frontier returns unowned areas neighbouring the territory of the author, in table order.
+func (a *areaAllocation) frontier(index int) []string {
+	names := make([]string, 0)
+	for _, area := range a.areaInfo.Areas {
+		if _, ok := a.owners[area.Name]; ok {
+			continue
+		}
+		for _, neighbour := range a.areaInfo.Adjacency[area.Name] {
+			if owner, ok := a.owners[neighbour]; ok && owner == index {
+				names = append(names, area.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// moveBest moves the area which reduces the total difference between allocated and target sizes the most.
+// An area only moves to an author owning a neighbouring area, or owning no area yet.
+// It returns false if no move reduces the difference.
+func (a *areaAllocation) moveBest(targetSizes []float64) bool {
+	errorOf := func(index int, allocatedSize float64) float64 {
+		return math.Abs(allocatedSize - targetSizes[index])
+	}
+
+	bestArea, bestIndex, bestGain := "", -1, 1e-9
+	for _, area := range a.areaInfo.Areas {
+		owner, owned := a.owners[area.Name]
+
+		for index := range a.shares {
+			if owned && owner == index {
+				continue
+			}
+			if len(a.areaInfo.Adjacency) > 0 && a.allocatedSizes[index] > 0 && !a.neighbours(area.Name, index) {
+				continue
+			}
+
+			gain := errorOf(index, a.allocatedSizes[index]) - errorOf(index, a.allocatedSizes[index]+area.Size)
+			if owned {
+				gain += errorOf(owner, a.allocatedSizes[owner]) - errorOf(owner, a.allocatedSizes[owner]-area.Size)
+			}
+			if gain > bestGain {
+				bestArea, bestIndex, bestGain = area.Name, index, gain
+			}
+		}
+	}
+
+	if bestArea == "" {
+		return false
+	}
+	a.assign(bestArea, bestIndex)
+	return true
+}
+
+func (a *areaAllocation) neighbours(name string, index int) bool {
+	for _, neighbour := range a.areaInfo.Adjacency[name] {
+		if owner, ok := a.owners[neighbour]; ok && owner == index {
+			return true
+		}
+	}
+	return false
+}
+
+// fillLeftovers gives each unowned area to the neighbouring owner furthest below its target size.
+// Areas without any owned neighbour go to the author furthest below its target size.
+func (a *areaAllocation) fillLeftovers(targetSizes []float64) {
+	deficit := func(index int) float64 {
+		return targetSizes[index] - a.allocatedSizes[index]
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, area := range a.areaInfo.Areas {
+			if _, ok := a.owners[area.Name]; ok {
+				continue
+			}
+
+			selected := -1
+			for _, neighbour := range a.areaInfo.Adjacency[area.Name] {
+				owner, ok := a.owners[neighbour]
+				if ok && (selected == -1 || deficit(owner) > deficit(selected)) {
+					selected = owner
+				}
+			}
+			if selected != -1 {
+				a.assign(area.Name, selected)
+				changed = true
+			}
+		}
+	}
+
+	for _, area := range a.areaInfo.Areas {
+		if _, ok := a.owners[area.Name]; ok {
+			continue
+		}
+
+		selected := 0
+		for index := range a.shares {
+			if deficit(index) > deficit(selected) {
+				selected = index
+			}
+		}
+		a.assign(area.Name, selected)
+	}
+}
+
+func (a *areaAllocation) authorOwners() map[string]string {
+	owners := map[string]string{}
+	for name, index := range a.owners {
+		owners[name] = a.shares[index].author
+	}
+	return owners
+}
+
+type authorShare struct {
+	author     string
+	lines      float64
+	linesRatio float64
+}
+
+// rankAuthorShares returns authors ordered by their lines, the most first. Weighted lines are used if the result has them.
+func rankAuthorShares(result *CountLinesResult) []authorShare {
+	weights := result.Weights()
+	totalLines := float64(0)
+	for _, lines := range weights {
+		totalLines += lines
+	}
+
+	shares := make([]authorShare, 0)
+	for author, lines := range weights {
+		linesRatio := float64(0)
+		if totalLines > 0 {
+			linesRatio = lines / totalLines
+		}
+		shares = append(shares, authorShare{
+			author:     author,
+			lines:      lines,
+			linesRatio: linesRatio,
+		})
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		if shares[i].lines == shares[j].lines {
+			return shares[i].author < shares[j].author
+		} else {
+			return shares[i].lines > shares[j].lines
+		}
+	})
+
+	return shares
+}
+
+func totalAreaSizeOf(areaInfo *AreaInfo) float64 {
+	totalAreaSize := float64(0)
+	for _, area := range areaInfo.Areas {
+		totalAreaSize += area.Size
+	}
+	return totalAreaSize
+}
+
+// allocateBySizeOrder gives areas from the largest to the author with the highest priority.
+// Ties are broken by author rank.
+func allocateBySizeOrder(
+	ctx context.Context,
+	areaInfo *AreaInfo,
+	result *CountLinesResult,
+	priority func(share authorShare, allocatedSize float64, area Area, totalAreaSize float64) float64,
+) ([]*AreaAuthor, error) {
+	logger := LoggerFromContext(ctx)
+
+	shares := rankAuthorShares(result)
+	if len(shares) == 0 || len(areaInfo.Areas) == 0 {
+		return []*AreaAuthor{}, nil
+	}
+
+	totalAreaSize := totalAreaSizeOf(areaInfo)
+
+	areas := make([]Area, len(areaInfo.Areas))
+	copy(areas, areaInfo.Areas)
+	sort.SliceStable(areas, func(i, j int) bool {
+		return areas[i].Size > areas[j].Size
+	})
+
+	allocatedSizes := make([]float64, len(shares))
+	owners := map[string]string{}
+	for _, area := range areas {
+		selected, selectedPriority := -1, math.Inf(-1)
+		for index, share := range shares {
+			p := priority(share, allocatedSizes[index], area, totalAreaSize)
+			if p > selectedPriority {
+				selected, selectedPriority = index, p
+			}
+		}
+
+		logger.Debugf(
+			"allocate: area=%v, size=%v => author=%v, linesRatio=%.2f, priority=%.4f",
+			area.Name,
+			area.Size,
+			shares[selected].author,
+			shares[selected].linesRatio,
+			selectedPriority,
+		)
+
+		owners[area.Name] = shares[selected].author
+		allocatedSizes[selected] += area.Size
+	}
+
+	return newAreaAuthors(areaInfo, owners, shares), nil
+}
+
+// newAreaAuthors lists owned areas in table order. Authors are ranked by lines among the authors owning any area,
+// and area ratios are rounded so that they sum up to 1.
+func newAreaAuthors(areaInfo *AreaInfo, owners map[string]string, shares []authorShare) []*AreaAuthor {
+	authorRanks := map[string]int{}
+	for _, share := range shares {
+		for _, owner := range owners {
+			if owner == share.author {
+				authorRanks[share.author] = len(authorRanks) + 1
+				break
+			}
+		}
+	}
+
+	totalAreaSize := totalAreaSizeOf(areaInfo)
+
+	areaAuthors := make([]*AreaAuthor, 0)
+	fraction := float64(1)
+	for _, area := range areaInfo.Areas {
+		owner, ok := owners[area.Name]
+		if !ok {
+			continue
+		}
+
+		roundedAreaRatio := math.Round(area.Size/totalAreaSize*1000) / 1000
+		areaAuthors = append(areaAuthors, &AreaAuthor{
+			Area:       area,
+			AreaRatio:  roundedAreaRatio,
+			Author:     owner,
+			AuthorRank: authorRanks[owner],
+		})
+		fraction -= roundedAreaRatio
+	}
+
+	if len(areaAuthors) > 0 {
+		areaAuthors[len(areaAuthors)-1].AreaRatio += math.Round(fraction*1000) / 1000
+	}
+
+	return areaAuthors
+}
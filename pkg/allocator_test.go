@@ -0,0 +1,303 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"github.com/dlclark/regexp2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetAllocator(t *testing.T) {
+	for _, name := range []string{"", AllocationGreedy, AllocationLargestRemainder, AllocationDHondt, AllocationContiguous, AllocationStable} {
+		t.Run(name, func(t *testing.T) {
+			allocator, err := GetAllocator(name)
+			assert.NoError(t, err)
+			assert.NotNil(t, allocator)
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := GetAllocator("unknown")
+		assert.Error(t, err)
+	})
+}
+
+func TestAllocator(t *testing.T) {
+	areaInfo := AreaInfo{
+		Region: "TestArea",
+		Areas: []Area{
+			{Name: "Area100", Size: 100},
+			{Name: "Area75", Size: 75},
+			{Name: "Area50", Size: 50},
+			{Name: "Area25", Size: 25},
+			{Name: "Area20", Size: 20},
+			{Name: "Area15", Size: 15},
+			{Name: "Area10", Size: 10},
+			{Name: "Area5", Size: 5},
+		},
+	}
+
+	result := CountLinesResult{
+		LinesByAuthor: map[string]int{
+			"userA": 300,
+			"userB": 200,
+			"userC": 100,
+		},
+	}
+
+	testCases := []struct {
+		allocation string
+		authors    []string
+	}{
+		{
+			allocation: AllocationGreedy,
+			authors:    []string{"userA", "userB", "userA", "userB", "userC", "userC", "userC", "userC"},
+		},
+		{
+			allocation: AllocationLargestRemainder,
+			authors:    []string{"userA", "userB", "userA", "userC", "userB", "userC", "userC", "userB"},
+		},
+		{
+			allocation: AllocationDHondt,
+			authors:    []string{"userA", "userB", "userA", "userC", "userC", "userB", "userB", "userC"},
+		},
+	}
+
+	ranks := map[string]int{"userA": 1, "userB": 2, "userC": 3}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.allocation, func(t *testing.T) {
+			allocator, err := GetAllocator(testCase.allocation)
+			assert.NoError(t, err)
+
+			areaAuthors, err := allocator.Allocate(context.Background(), &areaInfo, &result)
+			assert.NoError(t, err)
+			assert.Equal(t, len(testCase.authors), len(areaAuthors))
+
+			totalRatio := float64(0)
+			for index, areaAuthor := range areaAuthors {
+				assert.Equal(t, areaInfo.Areas[index], areaAuthor.Area)
+				assert.Equal(t, testCase.authors[index], areaAuthor.Author)
+				assert.Equal(t, ranks[areaAuthor.Author], areaAuthor.AuthorRank)
+				totalRatio += areaAuthor.AreaRatio
+			}
+			assert.InDelta(t, float64(1), totalRatio, 0.0001)
+		})
+	}
+
+	t.Run("no authors", func(t *testing.T) {
+		allocator, err := GetAllocator(AllocationLargestRemainder)
+		assert.NoError(t, err)
+
+		areaAuthors, err := allocator.Allocate(context.Background(), &areaInfo, &CountLinesResult{
+			LinesByAuthor: map[string]int{},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []*AreaAuthor{}, areaAuthors)
+	})
+}
+
+func TestLargestRemainderAllocator(t *testing.T) {
+	allocator, err := GetAllocator(AllocationLargestRemainder)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		areas  int
+		lines  map[string]int
+		counts map[string]int
+	}{
+		{
+			// quotas 4.7, 3.3, 2.0
+			areas:  10,
+			lines:  map[string]int{"userA": 47, "userB": 33, "userC": 20},
+			counts: map[string]int{"userA": 5, "userB": 3, "userC": 2},
+		},
+		{
+			// quotas 2.4, 2.4, 1.2: the leftover goes to the higher ranked of the largest remainders
+			areas:  6,
+			lines:  map[string]int{"userA": 40, "userB": 40, "userC": 20},
+			counts: map[string]int{"userA": 3, "userB": 2, "userC": 1},
+		},
+		{
+			// quotas 0.6, 0.3, 0.1: every area is a leftover
+			areas:  1,
+			lines:  map[string]int{"userA": 60, "userB": 30, "userC": 10},
+			counts: map[string]int{"userA": 1},
+		},
+	}
+
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%v", index), func(t *testing.T) {
+			areaInfo := AreaInfo{Region: "TestArea", Areas: make([]Area, 0)}
+			for i := 0; i < testCase.areas; i++ {
+				areaInfo.Areas = append(areaInfo.Areas, Area{Name: fmt.Sprintf("Area%v", i), Size: 1})
+			}
+
+			areaAuthors, err := allocator.Allocate(context.Background(), &areaInfo, &CountLinesResult{LinesByAuthor: testCase.lines})
+			assert.NoError(t, err)
+
+			counts := map[string]int{}
+			for _, areaAuthor := range areaAuthors {
+				counts[areaAuthor.Author]++
+			}
+			assert.Equal(t, testCase.counts, counts)
+		})
+	}
+}
+
+func TestContiguousAllocator(t *testing.T) {
+	allocator, err := GetAllocator(AllocationContiguous)
+	assert.NoError(t, err)
+
+	t.Run("test region", func(t *testing.T) {
+		areaInfo, err := GetAreaInfo("__TEST")
+		assert.NoError(t, err)
+
+		areaAuthors, err := allocator.Allocate(context.Background(), areaInfo, &CountLinesResult{
+			LinesByAuthor: map[string]int{
+				"userA": 50,
+				"userB": 10,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []*AreaAuthor{
+			{Area: areaInfo.Areas[0], AreaRatio: 0.5, Author: "userA", AuthorRank: 1},
+			{Area: areaInfo.Areas[1], AreaRatio: 0.333, Author: "userA", AuthorRank: 1},
+			{Area: areaInfo.Areas[2], AreaRatio: 0.167, Author: "userB", AuthorRank: 2},
+		}, areaAuthors)
+	})
+
+	t.Run("territories are connected", func(t *testing.T) {
+		areaInfo, err := GetAreaInfo("JP")
+		assert.NoError(t, err)
+
+		areaAuthors, err := allocator.Allocate(context.Background(), areaInfo, &CountLinesResult{
+			LinesByAuthor: map[string]int{
+				"userA": 4000,
+				"userB": 2500,
+				"userC": 1800,
+				"userD": 900,
+				"userE": 500,
+				"userF": 200,
+				"userG": 100,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, len(areaInfo.Areas), len(areaAuthors))
+
+		territories := map[string]map[string]bool{}
+		for _, areaAuthor := range areaAuthors {
+			if territories[areaAuthor.Author] == nil {
+				territories[areaAuthor.Author] = map[string]bool{}
+			}
+			territories[areaAuthor.Author][areaAuthor.Area.Name] = true
+		}
+
+		for author, territory := range territories {
+			assert.True(t, isConnected(areaInfo.Adjacency, territory), author)
+		}
+	})
+
+	t.Run("fall back without adjacency", func(t *testing.T) {
+		areaInfo := AreaInfo{
+			Region: "TestArea",
+			Areas: []Area{
+				{Name: "Area30", Size: 30},
+				{Name: "Area10", Size: 10},
+			},
+		}
+
+		areaAuthors, err := allocator.Allocate(context.Background(), &areaInfo, &CountLinesResult{
+			LinesByAuthor: map[string]int{
+				"userA": 3,
+				"userB": 1,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "userA", areaAuthors[0].Author)
+		assert.Equal(t, "userB", areaAuthors[1].Author)
+	})
+}
+
+func TestStableAllocator(t *testing.T) {
+	areaInfo, err := GetAreaInfo("JP")
+	assert.NoError(t, err)
+
+	filter := regexp2.MustCompile(".+", 0)
+	snapshots := []map[string]int{
+		{"userA": 4000, "userB": 2500, "userC": 1800, "userD": 900, "userE": 500},
+		{"userA": 4010, "userB": 2490, "userC": 1810, "userD": 890, "userE": 500},
+		{"userA": 4010, "userB": 2490, "userC": 1810, "userD": 890, "userE": 500, "userF": 1200},
+	}
+
+	stable, err := GetAllocator(AllocationStable)
+	assert.NoError(t, err)
+
+	owners := func(areaAuthors []*AreaAuthor) map[string]string {
+		owners := map[string]string{}
+		for _, areaAuthor := range areaAuthors {
+			owners[areaAuthor.Area.Name] = areaAuthor.Author
+		}
+		return owners
+	}
+
+	previous := map[string]string{}
+	for index, linesByAuthor := range snapshots {
+		result := CountLinesResult{
+			Filter:        filter,
+			LinesByAuthor: linesByAuthor,
+		}
+
+		areaAuthors, err := stable.Allocate(context.Background(), areaInfo, &result)
+		assert.NoError(t, err)
+		assert.Equal(t, len(areaInfo.Areas), len(areaAuthors))
+
+		current := owners(areaAuthors)
+		changed := 0
+		for name, owner := range current {
+			if previous[name] != owner {
+				changed++
+			}
+		}
+
+		switch index {
+		case 1:
+			assert.Equal(t, 0, changed)
+		case 2:
+			assert.Contains(t, current, "Hokkaido")
+			assert.Less(t, changed, len(areaInfo.Areas)/2)
+
+			found := false
+			for _, owner := range current {
+				found = found || owner == "userF"
+			}
+			assert.True(t, found)
+		}
+
+		previous = current
+	}
+}
+
+func isConnected(adjacency map[string][]string, areas map[string]bool) bool {
+	start := ""
+	for name := range areas {
+		start = name
+		break
+	}
+
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, neighbour := range adjacency[name] {
+			if areas[neighbour] && !visited[neighbour] {
+				visited[neighbour] = true
+				queue = append(queue, neighbour)
+			}
+		}
+	}
+
+	return len(visited) == len(areas)
+}
@@ -1,8 +1,8 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math"
 	"sort"
 )
@@ -14,15 +14,17 @@ type AreaAuthor struct {
 	AuthorRank int
 }
 
-func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+func AllocateAreas(ctx context.Context, areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor, error) {
+	logger := LoggerFromContext(ctx)
+
 	type rank struct {
 		author     string
-		lines      int
+		lines      float64
 		linesRatio float64
 		areaRatio  float64
 	}
 
-	log.Printf(
+	logger.Debugf(
 		"start GetAreaAuthors: areaInfo.Region=%+v, result.NameByAuthor=%+v, result.LinesByAuthor=%+v, result.MatchedFiles=%+v",
 		areaInfo.Region,
 		len(result.NameByAuthor),
@@ -34,20 +36,21 @@ func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor,
 		return []*AreaAuthor{}, nil
 	}
 
-	totalAuthors, totalLines := 0, 0
-	for _, lines := range result.LinesByAuthor {
+	weights := result.Weights()
+	totalAuthors, totalLines := 0, float64(0)
+	for _, lines := range weights {
 		totalLines += lines
 		totalAuthors++
 	}
 
-	log.Printf("count: totalAuthors=%v, totalLines=%v", totalAuthors, totalLines)
+	logger.Debugf("count: totalAuthors=%v, totalLines=%v", totalAuthors, totalLines)
 
 	ranks := make([]rank, 0)
-	for author, lines := range result.LinesByAuthor {
+	for author, lines := range weights {
 		ranks = append(ranks, rank{
 			author:     author,
 			lines:      lines,
-			linesRatio: float64(lines) / float64(totalLines),
+			linesRatio: lines / totalLines,
 			areaRatio:  0,
 		})
 	}
@@ -60,16 +63,16 @@ func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor,
 		}
 	})
 
-	log.Printf("ranks: count=%+v", len(ranks))
+	logger.Debugf("ranks: count=%+v", len(ranks))
 
 	totalAreaSize := float64(0)
 	for _, area := range areaInfo.Areas {
 		totalAreaSize += area.Size
 	}
 
-	log.Printf("count: areaCount=%v totalAreaSize=%v", len(areaInfo.Areas), totalAreaSize)
+	logger.Debugf("count: areaCount=%v totalAreaSize=%v", len(areaInfo.Areas), totalAreaSize)
 
-	log.Printf("start kunitori!")
+	logger.Debugf("start kunitori!")
 
 	areaAuthors := make([]*AreaAuthor, 0)
 	fraction := float64(1)
@@ -79,7 +82,7 @@ func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor,
 		author := ""
 		for index, rank := range ranks {
 			if rank.linesRatio >= rank.areaRatio+areaRatio {
-				log.Printf(
+				logger.Debugf(
 					"allocate: area=%v, areaRatio=%.2f => author=%v, linesRatio=%.2f, authorAreaRatio=%.2f => %.2f",
 					area.Name,
 					areaRatio,
@@ -96,7 +99,7 @@ func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor,
 		}
 
 		if author == "" {
-			log.Printf("skip: area=%v, areaRatio=%v", area.Name, areaRatio)
+			logger.Debugf("skip: area=%v, areaRatio=%v", area.Name, areaRatio)
 			continue
 		}
 
@@ -126,7 +129,7 @@ func AllocateAreas(areaInfo *AreaInfo, result *CountLinesResult) ([]*AreaAuthor,
 	roundedFraction := math.Round(fraction*1000) / 1000
 	areaAuthors[len(areaAuthors)-1].AreaRatio += roundedFraction
 
-	log.Printf("complete kunitori: areaAuthors=%v, roundedFraction=%v", len(areaAuthors), roundedFraction)
+	logger.Debugf("complete kunitori: areaAuthors=%v, roundedFraction=%v", len(areaAuthors), roundedFraction)
 
 	return areaAuthors, nil
 }
@@ -139,6 +142,30 @@ type Area struct {
 type AreaInfo struct {
 	Region string
 	Areas  []Area
+	// Adjacency lists neighbouring areas of each area. Regions without it cannot be allocated contiguously.
+	Adjacency map[string][]string
+	// Tiles places each area on a grid of {column, row} to draw the region as a tile map.
+	Tiles map[string][2]int
+	// DisplayNames gives names of areas shown in each language. Areas are shown by Name in the other languages.
+	DisplayNames map[string]map[string]string
+}
+
+// DisplayName returns the name of area shown in lang.
+func (areaInfo *AreaInfo) DisplayName(area string, lang string) string {
+	if name, ok := areaInfo.DisplayNames[lang][area]; ok {
+		return name
+	}
+	return area
+}
+
+// newAdjacency builds symmetric adjacency from pairs of neighbouring areas.
+func newAdjacency(pairs [][2]string) map[string][]string {
+	adjacency := map[string][]string{}
+	for _, pair := range pairs {
+		adjacency[pair[0]] = append(adjacency[pair[0]], pair[1])
+		adjacency[pair[1]] = append(adjacency[pair[1]], pair[0])
+	}
+	return adjacency
 }
 
 func GetAreaInfo(region string) (*AreaInfo, error) {
@@ -151,6 +178,22 @@ func GetAreaInfo(region string) (*AreaInfo, error) {
 				{Name: "Area20", Size: 20},
 				{Name: "Area10", Size: 10},
 			},
+			Adjacency: newAdjacency([][2]string{
+				{"Area30", "Area20"},
+				{"Area20", "Area10"},
+			}),
+			Tiles: map[string][2]int{
+				"Area30": {0, 0},
+				"Area20": {1, 0},
+				"Area10": {2, 0},
+			},
+			DisplayNames: map[string]map[string]string{
+				LangJa: {
+					"Area30": "エリア30",
+					"Area20": "エリア20",
+					"Area10": "エリア10",
+				},
+			},
 		}, nil
 	case "JP":
 		return &AreaInfo{
@@ -204,6 +247,202 @@ func GetAreaInfo(region string) (*AreaInfo, error) {
 				{Name: "Kagoshima", Size: 9187},
 				{Name: "Okinawa", Size: 2281},
 			},
+			// prefectures sharing a land border, or linked by a tunnel, bridge or sea route
+			Adjacency: newAdjacency([][2]string{
+				{"Hokkaido", "Aomori"},
+				{"Aomori", "Iwate"},
+				{"Aomori", "Akita"},
+				{"Iwate", "Akita"},
+				{"Iwate", "Miyagi"},
+				{"Miyagi", "Akita"},
+				{"Miyagi", "Yamagata"},
+				{"Miyagi", "Fukushima"},
+				{"Akita", "Yamagata"},
+				{"Yamagata", "Fukushima"},
+				{"Yamagata", "Niigata"},
+				{"Fukushima", "Ibaraki"},
+				{"Fukushima", "Tochigi"},
+				{"Fukushima", "Gunma"},
+				{"Fukushima", "Niigata"},
+				{"Ibaraki", "Tochigi"},
+				{"Ibaraki", "Saitama"},
+				{"Ibaraki", "Chiba"},
+				{"Tochigi", "Gunma"},
+				{"Tochigi", "Saitama"},
+				{"Gunma", "Saitama"},
+				{"Gunma", "Niigata"},
+				{"Gunma", "Nagano"},
+				{"Saitama", "Chiba"},
+				{"Saitama", "Tokyo"},
+				{"Saitama", "Yamanashi"},
+				{"Saitama", "Nagano"},
+				{"Chiba", "Tokyo"},
+				{"Chiba", "Kanagawa"},
+				{"Tokyo", "Kanagawa"},
+				{"Tokyo", "Yamanashi"},
+				{"Kanagawa", "Yamanashi"},
+				{"Kanagawa", "Shizuoka"},
+				{"Niigata", "Toyama"},
+				{"Niigata", "Nagano"},
+				{"Toyama", "Ishikawa"},
+				{"Toyama", "Nagano"},
+				{"Toyama", "Gifu"},
+				{"Ishikawa", "Fukui"},
+				{"Ishikawa", "Gifu"},
+				{"Fukui", "Gifu"},
+				{"Fukui", "Shiga"},
+				{"Fukui", "Kyoto"},
+				{"Yamanashi", "Nagano"},
+				{"Yamanashi", "Shizuoka"},
+				{"Nagano", "Gifu"},
+				{"Nagano", "Shizuoka"},
+				{"Nagano", "Aichi"},
+				{"Gifu", "Aichi"},
+				{"Gifu", "Mie"},
+				{"Gifu", "Shiga"},
+				{"Shizuoka", "Aichi"},
+				{"Aichi", "Mie"},
+				{"Mie", "Shiga"},
+				{"Mie", "Kyoto"},
+				{"Mie", "Nara"},
+				{"Mie", "Wakayama"},
+				{"Shiga", "Kyoto"},
+				{"Kyoto", "Osaka"},
+				{"Kyoto", "Hyogo"},
+				{"Kyoto", "Nara"},
+				{"Osaka", "Hyogo"},
+				{"Osaka", "Nara"},
+				{"Osaka", "Wakayama"},
+				{"Hyogo", "Tottori"},
+				{"Hyogo", "Okayama"},
+				{"Hyogo", "Tokushima"},
+				{"Nara", "Wakayama"},
+				{"Tottori", "Shimane"},
+				{"Tottori", "Okayama"},
+				{"Tottori", "Hiroshima"},
+				{"Shimane", "Hiroshima"},
+				{"Shimane", "Yamaguchi"},
+				{"Okayama", "Hiroshima"},
+				{"Okayama", "Kagawa"},
+				{"Hiroshima", "Yamaguchi"},
+				{"Hiroshima", "Ehime"},
+				{"Yamaguchi", "Fukuoka"},
+				{"Tokushima", "Kagawa"},
+				{"Tokushima", "Ehime"},
+				{"Tokushima", "Kochi"},
+				{"Kagawa", "Ehime"},
+				{"Ehime", "Kochi"},
+				{"Fukuoka", "Saga"},
+				{"Fukuoka", "Kumamoto"},
+				{"Fukuoka", "Oita"},
+				{"Saga", "Nagasaki"},
+				{"Kumamoto", "Oita"},
+				{"Kumamoto", "Miyazaki"},
+				{"Kumamoto", "Kagoshima"},
+				{"Oita", "Miyazaki"},
+				{"Miyazaki", "Kagoshima"},
+				{"Kagoshima", "Okinawa"},
+			}),
+			Tiles: map[string][2]int{
+				"Hokkaido":  {12, 0},
+				"Aomori":    {12, 1},
+				"Akita":     {11, 2},
+				"Iwate":     {12, 2},
+				"Yamagata":  {11, 3},
+				"Miyagi":    {12, 3},
+				"Ishikawa":  {9, 4},
+				"Toyama":    {10, 4},
+				"Niigata":   {11, 4},
+				"Fukushima": {12, 4},
+				"Shimane":   {3, 5},
+				"Tottori":   {4, 5},
+				"Hyogo":     {5, 5},
+				"Kyoto":     {6, 5},
+				"Shiga":     {7, 5},
+				"Fukui":     {8, 5},
+				"Gifu":      {9, 5},
+				"Nagano":    {10, 5},
+				"Gunma":     {11, 5},
+				"Tochigi":   {12, 5},
+				"Ibaraki":   {13, 5},
+				"Yamaguchi": {2, 6},
+				"Hiroshima": {3, 6},
+				"Okayama":   {4, 6},
+				"Osaka":     {5, 6},
+				"Nara":      {6, 6},
+				"Mie":       {7, 6},
+				"Aichi":     {8, 6},
+				"Shizuoka":  {9, 6},
+				"Yamanashi": {10, 6},
+				"Saitama":   {11, 6},
+				"Tokyo":     {12, 6},
+				"Chiba":     {13, 6},
+				"Saga":      {0, 7},
+				"Fukuoka":   {1, 7},
+				"Ehime":     {3, 7},
+				"Kagawa":    {4, 7},
+				"Wakayama":  {6, 7},
+				"Kanagawa":  {12, 7},
+				"Nagasaki":  {0, 8},
+				"Kumamoto":  {1, 8},
+				"Oita":      {2, 8},
+				"Kochi":     {3, 8},
+				"Tokushima": {4, 8},
+				"Kagoshima": {1, 9},
+				"Miyazaki":  {2, 9},
+				"Okinawa":   {0, 10},
+			},
+			DisplayNames: map[string]map[string]string{
+				LangJa: {
+					"Hokkaido":  "北海道",
+					"Aomori":    "青森県",
+					"Iwate":     "岩手県",
+					"Miyagi":    "宮城県",
+					"Akita":     "秋田県",
+					"Yamagata":  "山形県",
+					"Fukushima": "福島県",
+					"Ibaraki":   "茨城県",
+					"Tochigi":   "栃木県",
+					"Gunma":     "群馬県",
+					"Saitama":   "埼玉県",
+					"Chiba":     "千葉県",
+					"Tokyo":     "東京都",
+					"Kanagawa":  "神奈川県",
+					"Niigata":   "新潟県",
+					"Toyama":    "富山県",
+					"Ishikawa":  "石川県",
+					"Fukui":     "福井県",
+					"Yamanashi": "山梨県",
+					"Nagano":    "長野県",
+					"Gifu":      "岐阜県",
+					"Shizuoka":  "静岡県",
+					"Aichi":     "愛知県",
+					"Mie":       "三重県",
+					"Shiga":     "滋賀県",
+					"Kyoto":     "京都府",
+					"Osaka":     "大阪府",
+					"Hyogo":     "兵庫県",
+					"Nara":      "奈良県",
+					"Wakayama":  "和歌山県",
+					"Tottori":   "鳥取県",
+					"Shimane":   "島根県",
+					"Okayama":   "岡山県",
+					"Hiroshima": "広島県",
+					"Yamaguchi": "山口県",
+					"Tokushima": "徳島県",
+					"Kagawa":    "香川県",
+					"Ehime":     "愛媛県",
+					"Kochi":     "高知県",
+					"Fukuoka":   "福岡県",
+					"Saga":      "佐賀県",
+					"Nagasaki":  "長崎県",
+					"Kumamoto":  "熊本県",
+					"Oita":      "大分県",
+					"Miyazaki":  "宮崎県",
+					"Kagoshima": "鹿児島県",
+					"Okinawa":   "沖縄県",
+				},
+			},
 		}, nil
 	}
 
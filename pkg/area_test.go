@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -92,7 +93,7 @@ func TestAllocateAreas(t *testing.T) {
 
 	for index, testCase := range testCases {
 		t.Run(fmt.Sprintf("case_%v", index), func(t *testing.T) {
-			areaAuthors, err := AllocateAreas(testCase.areaInfo, testCase.result)
+			areaAuthors, err := AllocateAreas(context.Background(), testCase.areaInfo, testCase.result)
 			assert.NoError(t, err)
 			assert.Equal(t, len(testCase.areaInfo.Areas), len(areaAuthors))
 			assert.Equal(t, testCase.areaAuthors, areaAuthors)
@@ -116,4 +117,33 @@ func TestGetAreaInfo(t *testing.T) {
 		Name: "Hokkaido",
 		Size: float64(83424),
 	}, areaInfo.Areas[0])
+
+	areaNames := map[string]bool{}
+	for _, area := range areaInfo.Areas {
+		areaNames[area.Name] = true
+	}
+	for _, area := range areaInfo.Areas {
+		assert.NotEmpty(t, areaInfo.Adjacency[area.Name], area.Name)
+		for _, neighbour := range areaInfo.Adjacency[area.Name] {
+			assert.True(t, areaNames[neighbour], neighbour)
+			assert.Contains(t, areaInfo.Adjacency[neighbour], area.Name)
+		}
+	}
+	assert.True(t, isConnected(areaInfo.Adjacency, areaNames))
+
+	assert.Equal(t, len(areaInfo.Areas), len(areaInfo.Tiles))
+	tileAreas := map[[2]int]string{}
+	for _, area := range areaInfo.Areas {
+		tile, ok := areaInfo.Tiles[area.Name]
+		assert.True(t, ok, area.Name)
+		assert.Empty(t, tileAreas[tile], area.Name)
+		tileAreas[tile] = area.Name
+	}
+
+	assert.Equal(t, len(areaInfo.Areas), len(areaInfo.DisplayNames[LangJa]))
+	for _, area := range areaInfo.Areas {
+		assert.NotEqual(t, area.Name, areaInfo.DisplayName(area.Name, LangJa), area.Name)
+		assert.Equal(t, area.Name, areaInfo.DisplayName(area.Name, LangEn), area.Name)
+	}
+	assert.Equal(t, "北海道", areaInfo.DisplayName("Hokkaido", LangJa))
 }
@@ -0,0 +1,253 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BusFactorDefaultThreshold is the share of lines one author owns above which a directory is at risk.
+const BusFactorDefaultThreshold = 0.8
+
+type BusFactorOptions struct {
+	RepositoryUrl    string
+	RepositoryPath   string
+	CountLinesOption *CountLinesOption
+	// Depth is the deepest level of directories to report. The root directory is level 0.
+	Depth int
+	// Threshold is the share of lines of a directory, from 0 to 1, above which a single owner puts it at risk.
+	Threshold float64
+	Logger    Logger
+}
+
+type BusFactorAuthor struct {
+	Email string  `json:"email"`
+	Lines int     `json:"lines"`
+	Ratio float64 `json:"ratio"`
+}
+
+type BusFactorDirectory struct {
+	Path  string `json:"path"`
+	Lines int    `json:"lines"`
+	// BusFactor is the smallest number of authors who own more than half of the lines.
+	BusFactor int               `json:"busFactor"`
+	AtRisk    bool              `json:"atRisk"`
+	Authors   []BusFactorAuthor `json:"authors"`
+}
+
+type BusFactorFilter struct {
+	FilterRegex string               `json:"filterRegex"`
+	Directories []BusFactorDirectory `json:"directories"`
+}
+
+type BusFactorReport struct {
+	Repository  string            `json:"repository"`
+	Hash        string            `json:"hash"`
+	CommittedAt time.Time         `json:"committedAt"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Depth       int               `json:"depth"`
+	Threshold   float64           `json:"threshold"`
+	Filters     []BusFactorFilter `json:"filters"`
+}
+
+// ReportBusFactor counts lines at HEAD and reports how concentrated the ownership of each directory is.
+func ReportBusFactor(ctx context.Context, options *BusFactorOptions) (*BusFactorReport, error) {
+	if options.Logger != nil {
+		ctx = WithLogger(ctx, options.Logger)
+	}
+	logger := LoggerFromContext(ctx)
+
+	if options.Depth < 0 {
+		return nil, fmt.Errorf("invalid depth: depth=%v", options.Depth)
+	}
+	if options.Threshold <= 0 || options.Threshold > 1 {
+		return nil, fmt.Errorf("invalid threshold: threshold=%v", options.Threshold)
+	}
+
+	if !IsUseGitCommandProvided() {
+		logger.Warnf(
+			"If the environment variable %v is not set, blame operation will be very slow.",
+			KunitoriUseGitCommandProvidedKey,
+		)
+	}
+
+	repository, repositoryRemoteLocation, closeRepository, err := openTargetRepository(ctx, options.RepositoryUrl, options.RepositoryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRepository()
+
+	reference, err := repository.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repository.CommitObject(reference.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("count lines: hash=%v", commit.Hash)
+
+	results, err := CountLines(ctx, repository, commit, options.CountLinesOption)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BusFactorReport{
+		Repository:  GetRemoteUrl(repositoryRemoteLocation),
+		Hash:        commit.Hash.String(),
+		CommittedAt: commit.Author.When.UTC(),
+		GeneratedAt: time.Now().UTC(),
+		Depth:       options.Depth,
+		Threshold:   options.Threshold,
+		Filters:     make([]BusFactorFilter, 0),
+	}
+	for _, result := range results {
+		report.Filters = append(report.Filters, BusFactorFilter{
+			FilterRegex: result.Filter.String(),
+			Directories: aggregateBusFactor(result, options.Depth, options.Threshold),
+		})
+	}
+
+	return report, nil
+}
+
+// aggregateBusFactor rolls lines of files up into their directories down to depth.
+func aggregateBusFactor(result *CountLinesResult, depth int, threshold float64) []BusFactorDirectory {
+	linesByDirectory := map[string]map[string]int{}
+	for file, linesByAuthor := range result.LinesByFile {
+		for _, directory := range parentDirectories(file, depth) {
+			if linesByDirectory[directory] == nil {
+				linesByDirectory[directory] = map[string]int{}
+			}
+			for author, lines := range linesByAuthor {
+				linesByDirectory[directory][author] += lines
+			}
+		}
+	}
+
+	directories := make([]BusFactorDirectory, 0, len(linesByDirectory))
+	for directoryPath, linesByAuthor := range linesByDirectory {
+		directories = append(directories, newBusFactorDirectory(directoryPath, linesByAuthor, threshold))
+	}
+
+	sort.SliceStable(directories, func(i, j int) bool {
+		return directories[i].Path < directories[j].Path
+	})
+
+	return directories
+}
+
+// parentDirectories returns the directories containing file from the root "." down to depth.
+func parentDirectories(file string, depth int) []string {
+	directories := []string{"."}
+	parts := strings.Split(path.Dir(file), "/")
+	if parts[0] == "." {
+		return directories
+	}
+	for i := 1; i <= depth && i <= len(parts); i++ {
+		directories = append(directories, strings.Join(parts[:i], "/"))
+	}
+	return directories
+}
+
+func newBusFactorDirectory(directoryPath string, linesByAuthor map[string]int, threshold float64) BusFactorDirectory {
+	totalLines := 0
+	for _, lines := range linesByAuthor {
+		totalLines += lines
+	}
+
+	authors := make([]BusFactorAuthor, 0, len(linesByAuthor))
+	for author, lines := range linesByAuthor {
+		ratio := float64(0)
+		if totalLines > 0 {
+			ratio = float64(lines) / float64(totalLines)
+		}
+		authors = append(authors, BusFactorAuthor{
+			Email: author,
+			Lines: lines,
+			Ratio: ratio,
+		})
+	}
+
+	sort.SliceStable(authors, func(i, j int) bool {
+		if authors[i].Lines == authors[j].Lines {
+			return authors[i].Email < authors[j].Email
+		} else {
+			return authors[i].Lines > authors[j].Lines
+		}
+	})
+
+	busFactor, ownedLines := 0, 0
+	for _, author := range authors {
+		if ownedLines*2 > totalLines {
+			break
+		}
+		ownedLines += author.Lines
+		busFactor++
+	}
+
+	return BusFactorDirectory{
+		Path:      directoryPath,
+		Lines:     totalLines,
+		BusFactor: busFactor,
+		AtRisk:    len(authors) > 0 && authors[0].Ratio > threshold,
+		Authors:   authors,
+	}
+}
+
+// RenderBusFactorMarkdown renders report as Markdown tables, one for each filter.
+func RenderBusFactorMarkdown(report *BusFactorReport) (string, error) {
+	if report == nil {
+		return "", errors.New("report is nil")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("# Bus factor\n\n")
+	builder.WriteString(fmt.Sprintf("- Repository: %v\n", report.Repository))
+	builder.WriteString(fmt.Sprintf("- Revision: %v (%v)\n", report.Hash, report.CommittedAt.Format(time.RFC3339)))
+	builder.WriteString(fmt.Sprintf("- At risk: one author owns more than %v of a directory\n", formatPercent(report.Threshold)))
+
+	for _, filter := range report.Filters {
+		builder.WriteString(fmt.Sprintf("\n## `%v`\n\n", escapeMarkdownCode(filter.FilterRegex)))
+		builder.WriteString("| Directory | Lines | Bus factor | Top author | Share | At risk |\n")
+		builder.WriteString("| --- | ---: | ---: | --- | ---: | :---: |\n")
+		for _, directory := range filter.Directories {
+			topAuthor, topRatio := "", float64(0)
+			if len(directory.Authors) > 0 {
+				topAuthor, topRatio = directory.Authors[0].Email, directory.Authors[0].Ratio
+			}
+			atRisk := ""
+			if directory.AtRisk {
+				atRisk = "⚠️"
+			}
+			builder.WriteString(fmt.Sprintf(
+				"| %v | %v | %v | %v | %v | %v |\n",
+				escapeMarkdownTableCell(directory.Path),
+				directory.Lines,
+				directory.BusFactor,
+				escapeMarkdownTableCell(topAuthor),
+				formatPercent(topRatio),
+				atRisk,
+			))
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func formatPercent(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+func escapeMarkdownTableCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+func escapeMarkdownCode(value string) string {
+	return strings.ReplaceAll(value, "`", "'")
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"context"
+	"github.com/dlclark/regexp2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParentDirectories(t *testing.T) {
+	assert.Equal(t, []string{"."}, parentDirectories("main.go", 2))
+	assert.Equal(t, []string{".", "pkg"}, parentDirectories("pkg/git.go", 2))
+	assert.Equal(t, []string{".", "a", "a/b"}, parentDirectories("a/b/c/d.go", 2))
+	assert.Equal(t, []string{"."}, parentDirectories("a/b/c/d.go", 0))
+}
+
+func TestAggregateBusFactor(t *testing.T) {
+	result := &CountLinesResult{
+		LinesByFile: map[string]map[string]int{
+			"main.go":      {"alice@example.com": 10},
+			"pkg/a.go":     {"alice@example.com": 45, "bob@example.com": 5},
+			"pkg/sub/b.go": {"bob@example.com": 20, "carol@example.com": 20},
+		},
+	}
+
+	directories := aggregateBusFactor(result, 1, 0.8)
+	assert.Equal(t, []BusFactorDirectory{
+		{
+			Path:      ".",
+			Lines:     100,
+			BusFactor: 1,
+			AtRisk:    false,
+			Authors: []BusFactorAuthor{
+				{Email: "alice@example.com", Lines: 55, Ratio: 0.55},
+				{Email: "bob@example.com", Lines: 25, Ratio: 0.25},
+				{Email: "carol@example.com", Lines: 20, Ratio: 0.2},
+			},
+		},
+		{
+			Path:      "pkg",
+			Lines:     90,
+			BusFactor: 2,
+			AtRisk:    false,
+			Authors: []BusFactorAuthor{
+				{Email: "alice@example.com", Lines: 45, Ratio: 0.5},
+				{Email: "bob@example.com", Lines: 25, Ratio: 25.0 / 90.0},
+				{Email: "carol@example.com", Lines: 20, Ratio: 20.0 / 90.0},
+			},
+		},
+	}, directories)
+
+	directories = aggregateBusFactor(&CountLinesResult{
+		LinesByFile: map[string]map[string]int{
+			"pkg/a.go": {"alice@example.com": 9, "bob@example.com": 1},
+		},
+	}, 1, 0.8)
+	assert.Equal(t, 1, directories[1].BusFactor)
+	assert.True(t, directories[1].AtRisk)
+}
+
+func TestReportBusFactor(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+
+	commit := commitTestFiles(t, repository, map[string]string{
+		"main.go":  "package main\n\nfunc main() {}\n",
+		"pkg/a.go": "package pkg\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+	report, err := ReportBusFactor(context.Background(), &BusFactorOptions{
+		RepositoryPath: dir,
+		CountLinesOption: &CountLinesOption{
+			Filters: []*regexp2.Regexp{regexp2.MustCompile("\\.go$", 0)},
+		},
+		Depth:     1,
+		Threshold: BusFactorDefaultThreshold,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, commit.Hash.String(), report.Hash)
+	assert.Equal(t, 1, len(report.Filters))
+	assert.Equal(t, 2, len(report.Filters[0].Directories))
+	assert.Equal(t, 4, report.Filters[0].Directories[0].Lines)
+	assert.True(t, report.Filters[0].Directories[0].AtRisk)
+
+	markdown, err := RenderBusFactorMarkdown(report)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(markdown, "## `\\.go$`"))
+	assert.True(t, strings.Contains(markdown, "| pkg | 1 | 1 | alice@example.com | 100.0% | ⚠️ |"))
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		_, err := ReportBusFactor(context.Background(), &BusFactorOptions{
+			RepositoryPath:   dir,
+			CountLinesOption: &CountLinesOption{},
+			Threshold:        1.5,
+		})
+		assert.Error(t, err)
+	})
+}
@@ -9,11 +9,48 @@ import (
 //go:embed chart.html
 var chartHtml string
 
-func RenderChartHtml(generateResult *GenerateResult) (string, error) {
-	chartHtmlTemplate, err := template.New("chart").Parse(chartHtml)
+// RenderOptions customizes how GenerateResult is rendered.
+type RenderOptions struct {
+	// ChartTemplate renders the html format instead of the embedded chart.html if it is set.
+	ChartTemplate *template.Template
+}
+
+// NewChartTemplate parses text as a chart template executed with GenerateResult.
+// Besides the functions of html/template, templates can use:
+//
+//	formatPercent 0.123         -> "12.3%"
+//	shortHash .Hash             -> the first 7 characters of the hash
+//	repositoryUrl               -> the repository page, or empty if it is not hosted by a known provider
+//	commitUrl .Hash             -> the page of the commit, or empty if the repository is not hosted by a known provider
+//	userUrl .GitHubLogin        -> the GitHub profile of the login, or empty if login is empty
+//	lang                        -> the language of the result, e.g. "ja"
+//	t "totalLines"              -> the text of Messages in the language of the result
+//	areaName .Name              -> the name of the area shown in the language of the result
+//	messages                    -> all texts in the language of the result, e.g. to pass them to scripts
+//	areaNames                   -> names of all areas of the region shown in the language of the result
+func NewChartTemplate(text string) (*template.Template, error) {
+	return template.New("chart").Funcs(chartTemplateFuncs(&GenerateResult{})).Parse(text)
+}
+
+func RenderChartHtml(generateResult *GenerateResult, options *RenderOptions) (string, error) {
+	chartTemplate := (*template.Template)(nil)
+	if options != nil {
+		chartTemplate = options.ChartTemplate
+	}
+	if chartTemplate == nil {
+		var err error
+		chartTemplate, err = NewChartTemplate(chartHtml)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// cloned so that the template given by options can render other results with their own urls
+	chartHtmlTemplate, err := chartTemplate.Clone()
 	if err != nil {
 		return "", err
 	}
+	chartHtmlTemplate.Funcs(chartTemplateFuncs(generateResult))
 
 	buf := bytes.NewBufferString("")
 	err = chartHtmlTemplate.Execute(buf, generateResult)
@@ -23,3 +60,67 @@ func RenderChartHtml(generateResult *GenerateResult) (string, error) {
 
 	return buf.String(), nil
 }
+
+func chartTemplateFuncs(generateResult *GenerateResult) template.FuncMap {
+	return template.FuncMap{
+		"formatPercent": formatPercent,
+		"shortHash":     shortHash,
+		"repositoryUrl": func() string {
+			return repositoryUrlOf(generateResult)
+		},
+		"commitUrl": func(hash string) string {
+			repositoryUrl := repositoryUrlOf(generateResult)
+			if repositoryUrl == "" || hash == "" {
+				return ""
+			}
+			return repositoryUrl + "/tree/" + hash
+		},
+		"userUrl": func(login string) string {
+			if login == "" {
+				return ""
+			}
+			gitHubUrl := generateResult.GitHubUrl
+			if gitHubUrl == "" {
+				gitHubUrl = GitHubDefaultBaseUrl
+			}
+			return gitHubUrl + "/" + login
+		},
+		"lang": func() string {
+			return langOf(generateResult)
+		},
+		"t": func(key string) string {
+			if message, ok := messagesOf(langOf(generateResult))[key]; ok {
+				return message
+			}
+			return key
+		},
+		"areaName": func(name string) string {
+			if areaName, ok := areaNamesOf(generateResult.Region, langOf(generateResult))[name]; ok {
+				return areaName
+			}
+			return name
+		},
+		"messages": func() map[string]string {
+			return messagesOf(langOf(generateResult))
+		},
+		"areaNames": func() map[string]string {
+			return areaNamesOf(generateResult.Region, langOf(generateResult))
+		},
+	}
+}
+
+// repositoryUrlOf returns the repository page as chart.html links it.
+func repositoryUrlOf(generateResult *GenerateResult) string {
+	if generateResult.Source != "github" {
+		return ""
+	}
+	return generateResult.Repository
+}
+
+// shortHash abbreviates hash as git does by default.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
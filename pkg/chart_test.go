@@ -11,6 +11,7 @@ func TestRenderChartHtml(t *testing.T) {
 	generateResult := GenerateResult{
 		Repository:  "dummy",
 		Source:      "unknown",
+		GitHubUrl:   "https://github.com",
 		GeneratedAt: time.Now().UTC(),
 		Commits: []GenerateResultCommit{
 			{
@@ -43,7 +44,7 @@ func TestRenderChartHtml(t *testing.T) {
 		},
 	}
 
-	html, err := RenderChartHtml(&generateResult)
+	html, err := RenderChartHtml(&generateResult, nil)
 	assert.NoError(t, err)
 
 	chartHtmlFilePath := testOutPath("chart.html")
@@ -54,3 +55,49 @@ func TestRenderChartHtml(t *testing.T) {
 	_, err = file.Write([]byte(html))
 	assert.NoError(t, err)
 }
+
+func TestRenderChartHtml__template(t *testing.T) {
+	chartTemplate, err := NewChartTemplate(`<h1><a href="{{repositoryUrl}}">{{.Repository}}</a></h1>
+{{range .Commits}}<a href="{{commitUrl .Hash}}">{{shortHash .Hash}}</a>
+{{range .LineCounts}}{{range .Authors}}<a href="{{userUrl .GitHubLogin}}">{{.Name}}</a> {{formatPercent 0.25}}
+{{end}}{{end}}{{end}}`)
+	assert.NoError(t, err)
+
+	options := &RenderOptions{ChartTemplate: chartTemplate}
+	html, err := RenderChartHtml(newTestExportResult(), options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<h1><a href="https://github.com/yktakaha4/kunitori">https://github.com/yktakaha4/kunitori</a></h1>
+<a href="https://github.com/yktakaha4/kunitori/tree/2fa8fa83724e394a098890c40cc324fa90b080b5">2fa8fa8</a>
+<a href="https://github.com/alice">Alice</a> 25.0%
+<a href="">Bob, Jr.</a> 25.0%
+<a href=""></a> 25.0%
+`, html)
+
+	generateResult := newTestExportResult()
+	generateResult.Source = "unknown"
+	html, err = RenderChartHtml(generateResult, options)
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<h1><a href="">`)
+
+	chartTemplate, err = NewChartTemplate(`<html lang="{{lang}}">{{t "totalLines"}} {{t "unknownKey"}}
+{{range .Commits}}{{range .LineCounts}}{{range .Areas}}{{areaName .Name}} {{end}}{{end}}{{end}}
+<script>const messages = {{messages}}; const areaNames = {{areaNames}};</script>`)
+	assert.NoError(t, err)
+
+	generateResult.Lang = LangJa
+	html, err = RenderChartHtml(generateResult, &RenderOptions{ChartTemplate: chartTemplate})
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<html lang="ja">総行数 unknownKey`)
+	assert.Contains(t, html, "エリア30 エリア20 \n")
+	assert.Contains(t, html, `"Area30":"エリア30"`)
+	assert.Contains(t, html, `"revision":"リビジョン"`)
+
+	generateResult.Lang = ""
+	html, err = RenderChartHtml(generateResult, &RenderOptions{ChartTemplate: chartTemplate})
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<html lang="en">Total lines unknownKey`)
+	assert.Contains(t, html, "Area30 Area20 \n")
+
+	_, err = NewChartTemplate("{{unknownFunc}}")
+	assert.Error(t, err)
+}
@@ -0,0 +1,359 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	OwnershipBlame      = "blame"
+	OwnershipCodeOwners = "codeowners"
+)
+
+// CodeOwnersUnowned is the owner of files which are not matched by any CODEOWNERS rule.
+const CodeOwnersUnowned = "(unowned)"
+
+// CodeOwnersPaths are the locations of CODEOWNERS in the order GitHub looks them up.
+var CodeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+	regex   *regexp.Regexp
+}
+
+type CodeOwners struct {
+	Path  string
+	Rules []CodeOwnersRule
+}
+
+// ParseCodeOwners parses CODEOWNERS. Each line is a gitignore style pattern followed by owners.
+func ParseCodeOwners(reader io.Reader) (*CodeOwners, error) {
+	codeOwners := &CodeOwners{
+		Rules: make([]CodeOwnersRule, 0),
+	}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		owners := make([]string, 0)
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			owners = append(owners, field)
+		}
+
+		regex, err := codeOwnersPatternRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: line=%v, pattern=%v, err=%w", lineNumber, fields[0], err)
+		}
+
+		codeOwners.Rules = append(codeOwners.Rules, CodeOwnersRule{
+			Pattern: fields[0],
+			Owners:  owners,
+			regex:   regex,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return codeOwners, nil
+}
+
+// Owners returns the owners of file. The last matching rule takes precedence, and nil is returned if no rule has owners.
+func (c *CodeOwners) Owners(file string) []string {
+	for i := len(c.Rules) - 1; i >= 0; i-- {
+		rule := c.Rules[i]
+		if rule.regex.MatchString(file) {
+			if len(rule.Owners) == 0 {
+				return nil
+			}
+			return rule.Owners
+		}
+	}
+	return nil
+}
+
+func codeOwnersPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var builder strings.Builder
+	if anchored {
+		builder.WriteString("^")
+	} else {
+		builder.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				if i+2 < len(pattern) && pattern[i+2] == '/' {
+					builder.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					builder.WriteString(".*")
+					i++
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				builder.WriteString(regexp.QuoteMeta(string(pattern[i+1])))
+				i++
+			}
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	if directory {
+		builder.WriteString("/.*$")
+	} else if strings.HasSuffix(pattern, "*") {
+		// "docs/*" matches files directly under docs, not the nested ones
+		builder.WriteString("$")
+	} else {
+		builder.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(builder.String())
+}
+
+// FindCodeOwners reads CODEOWNERS of commit. nil is returned if commit has no CODEOWNERS.
+func FindCodeOwners(commit *object.Commit) (*CodeOwners, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range CodeOwnersPaths {
+		file, err := tree.File(path)
+		if errors.Is(err, object.ErrFileNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		reader, err := file.Reader()
+		if err != nil {
+			return nil, err
+		}
+		codeOwners, err := ParseCodeOwners(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS: path=%v, err=%w", path, err)
+		}
+		codeOwners.Path = path
+
+		return codeOwners, nil
+	}
+
+	return nil, nil
+}
+
+// CountLinesByCodeOwners counts lines of files matched by the filters of options, and attributes them to the owners declared in CODEOWNERS.
+// Lines of a file are split evenly among its owners, and lines of files without owners are attributed to CodeOwnersUnowned.
+func CountLinesByCodeOwners(ctx context.Context, commit *object.Commit, options *CountLinesOption) ([]*CountLinesResult, error) {
+	logger := LoggerFromContext(ctx)
+
+	logger.Debugf("start CountLinesByCodeOwners: commit=%+v, options=%+v", commit.Hash, options)
+
+	codeOwners, err := FindCodeOwners(commit)
+	if err != nil {
+		return nil, err
+	}
+	if codeOwners == nil {
+		logger.Warnf("CODEOWNERS not found: hash=%v", commit.Hash)
+		codeOwners = &CodeOwners{}
+	} else {
+		logger.Debugf("CODEOWNERS found: path=%v, rules=%v", codeOwners.Path, len(codeOwners.Rules))
+	}
+
+	results := make([]*CountLinesResult, 0)
+	for _, filter := range options.Filters {
+		results = append(results, &CountLinesResult{
+			Filter:        filter,
+			LinesByAuthor: map[string]int{},
+			NameByAuthor:  map[string]string{},
+			HashByAuthor:  map[string]string{},
+			MatchedFiles:  make([]string, 0),
+			LinesByFile:   map[string]map[string]int{},
+		})
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := matchFiles(ctx, tree, options.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	for fileIndex, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var lineCount *int
+		owners := codeOwners.Owners(file.Name)
+		if len(owners) == 0 {
+			owners = []string{CodeOwnersUnowned}
+		}
+
+		for _, result := range results {
+			isMatch, err := result.Filter.MatchString(file.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !isMatch {
+				continue
+			}
+
+			if lineCount == nil {
+				count, err := countFileLines(file)
+				if err != nil {
+					return nil, err
+				}
+				lineCount = &count
+			}
+
+			result.MatchedFiles = append(result.MatchedFiles, file.Name)
+			result.LinesByFile[file.Name] = map[string]int{}
+			for i, owner := range owners {
+				lines := *lineCount / len(owners)
+				if i < *lineCount%len(owners) {
+					lines++
+				}
+				result.LinesByAuthor[owner] += lines
+				result.LinesByFile[file.Name][owner] += lines
+				result.NameByAuthor[owner] = owner
+			}
+		}
+
+		if options.Progress != nil {
+			options.Progress(fileIndex+1, len(files), file.Name)
+		}
+	}
+
+	return results, nil
+}
+
+func countFileLines(file *object.File) (int, error) {
+	isBinary, err := file.IsBinary()
+	if err != nil {
+		return 0, err
+	}
+	if isBinary {
+		return 0, nil
+	}
+
+	lines, err := file.Lines()
+	if err != nil {
+		return 0, err
+	}
+	return len(lines), nil
+}
+
+// CodeOwnerLogin returns the GitHub login of owner if owner is a user like "@octocat".
+func CodeOwnerLogin(owner string) string {
+	if strings.HasPrefix(owner, "@") && !strings.Contains(owner, "/") {
+		return strings.TrimPrefix(owner, "@")
+	}
+	return ""
+}
+
+type OwnershipDiff struct {
+	Owner string `json:"owner"`
+	// DeclaredLines is the number of lines of the files owned by Owner.
+	DeclaredLines int `json:"declaredLines"`
+	// AuthoredLines is the number of lines in those files which are written by Owner.
+	AuthoredLines int     `json:"authoredLines"`
+	AuthoredRatio float64 `json:"authoredRatio"`
+	// UnmaintainedFiles are the files owned by Owner which contain no line written by Owner.
+	UnmaintainedFiles []string `json:"unmaintainedFiles"`
+}
+
+// DiffOwnership compares the declared ownership with the blame based one.
+// isOwner reports whether author is, or is a member of, owner.
+func DiffOwnership(declared *CountLinesResult, blamed *CountLinesResult, isOwner func(owner string, author string) bool) []OwnershipDiff {
+	diffByOwner := map[string]*OwnershipDiff{}
+	for _, file := range declared.MatchedFiles {
+		authorLines := blamed.LinesByFile[file]
+		fileLines := 0
+		for _, lines := range authorLines {
+			fileLines += lines
+		}
+
+		for owner := range declared.LinesByFile[file] {
+			if owner == CodeOwnersUnowned {
+				continue
+			}
+
+			diff, ok := diffByOwner[owner]
+			if !ok {
+				diff = &OwnershipDiff{
+					Owner:             owner,
+					UnmaintainedFiles: make([]string, 0),
+				}
+				diffByOwner[owner] = diff
+			}
+
+			authoredLines := 0
+			for author, lines := range authorLines {
+				if isOwner(owner, author) {
+					authoredLines += lines
+				}
+			}
+
+			diff.DeclaredLines += fileLines
+			diff.AuthoredLines += authoredLines
+			if fileLines > 0 && authoredLines == 0 {
+				diff.UnmaintainedFiles = append(diff.UnmaintainedFiles, file)
+			}
+		}
+	}
+
+	diffs := make([]OwnershipDiff, 0, len(diffByOwner))
+	for _, diff := range diffByOwner {
+		if diff.DeclaredLines > 0 {
+			diff.AuthoredRatio = float64(diff.AuthoredLines) / float64(diff.DeclaredLines)
+		}
+		sort.Strings(diff.UnmaintainedFiles)
+		diffs = append(diffs, *diff)
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].AuthoredRatio == diffs[j].AuthoredRatio {
+			return diffs[i].Owner < diffs[j].Owner
+		} else {
+			return diffs[i].AuthoredRatio < diffs[j].AuthoredRatio
+		}
+	})
+
+	return diffs
+}
@@ -0,0 +1,164 @@
+package pkg
+
+import (
+	"context"
+	"github.com/dlclark/regexp2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCodeOwners(t *testing.T) {
+	codeOwners, err := ParseCodeOwners(strings.NewReader(`
+# default owners
+*       @global-owner
+
+*.js    @js-owner # inline comment
+/build/logs/ @doctocat
+docs/*  docs@example.com
+apps/   @octocat
+**/logs @monalisa
+/scripts/ @doctocat @octocat
+/scripts/generated
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(codeOwners.Rules))
+
+	testCases := []struct {
+		file   string
+		owners []string
+	}{
+		{file: "README.md", owners: []string{"@global-owner"}},
+		{file: "src/index.js", owners: []string{"@js-owner"}},
+		{file: "build/logs/out.txt", owners: []string{"@monalisa"}},
+		{file: "build/logs.txt", owners: []string{"@global-owner"}},
+		{file: "docs/getting-started.md", owners: []string{"docs@example.com"}},
+		{file: "docs/build-app/troubleshooting.md", owners: []string{"@global-owner"}},
+		{file: "apps/main.go", owners: []string{"@octocat"}},
+		{file: "src/apps/main.go", owners: []string{"@octocat"}},
+		{file: "deep/nested/logs/a.txt", owners: []string{"@monalisa"}},
+		{file: "scripts/run.sh", owners: []string{"@doctocat", "@octocat"}},
+		{file: "scripts/generated/run.sh", owners: nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.file, func(t *testing.T) {
+			assert.Equal(t, testCase.owners, codeOwners.Owners(testCase.file))
+		})
+	}
+}
+
+func TestCodeOwnerLogin(t *testing.T) {
+	assert.Equal(t, "octocat", CodeOwnerLogin("@octocat"))
+	assert.Equal(t, "", CodeOwnerLogin("@org/team"))
+	assert.Equal(t, "", CodeOwnerLogin("octocat@example.com"))
+}
+
+func TestCountLinesByCodeOwners(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+
+	files := map[string]string{
+		".github/CODEOWNERS": "*.go @alice\n/pkg/ @bob @org/backend\n",
+		"CODEOWNERS":         "* @ignored\n",
+		"main.go":            "package main\n\nfunc main() {}\n",
+		"pkg/a.go":           "package pkg\n\nvar A = 1\n",
+		"README.md":          "# readme\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	workTree, err := repository.Worktree()
+	assert.NoError(t, err)
+	assert.NoError(t, workTree.AddGlob("."))
+	hash, err := workTree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+	commit, err := repository.CommitObject(hash)
+	assert.NoError(t, err)
+
+	codeOwners, err := FindCodeOwners(commit)
+	assert.NoError(t, err)
+	assert.Equal(t, ".github/CODEOWNERS", codeOwners.Path)
+
+	progressFiles := make([]string, 0)
+	results, err := CountLinesByCodeOwners(context.Background(), commit, &CountLinesOption{
+		Filters: []*regexp2.Regexp{
+			regexp2.MustCompile("\\.go$", 0),
+			regexp2.MustCompile("\\.md$", 0),
+		},
+		Progress: func(fileIndex int, fileCount int, file string) {
+			assert.Equal(t, 3, fileCount)
+			progressFiles = append(progressFiles, file)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(progressFiles))
+	assert.Equal(t, 2, len(results))
+
+	assert.Equal(t, map[string]int{"@alice": 3, "@bob": 2, "@org/backend": 1}, results[0].LinesByAuthor)
+	assert.Equal(t, map[string]map[string]int{
+		"main.go":  {"@alice": 3},
+		"pkg/a.go": {"@bob": 2, "@org/backend": 1},
+	}, results[0].LinesByFile)
+	assert.Equal(t, map[string]int{CodeOwnersUnowned: 1}, results[1].LinesByAuthor)
+}
+
+func TestDiffOwnership(t *testing.T) {
+	declared := &CountLinesResult{
+		MatchedFiles: []string{"main.go", "pkg/a.go", "README.md"},
+		LinesByFile: map[string]map[string]int{
+			"main.go":   {"@alice": 10},
+			"pkg/a.go":  {"@alice": 3, "@org/backend": 2},
+			"README.md": {CodeOwnersUnowned: 1},
+		},
+	}
+	blamed := &CountLinesResult{
+		LinesByFile: map[string]map[string]int{
+			"main.go":   {"alice@example.com": 4, "carol@example.com": 6},
+			"pkg/a.go":  {"bob@example.com": 5},
+			"README.md": {"alice@example.com": 1},
+		},
+	}
+	logins := map[string]string{"alice@example.com": "alice"}
+	teams := Teams{"@org/backend": {"bob@example.com"}}
+
+	diffs := DiffOwnership(declared, blamed, func(owner string, author string) bool {
+		if owner == "@"+logins[author] {
+			return true
+		}
+		for _, member := range teams[owner] {
+			if member == author {
+				return true
+			}
+		}
+		return false
+	})
+
+	assert.Equal(t, []OwnershipDiff{
+		{
+			Owner:             "@alice",
+			DeclaredLines:     15,
+			AuthoredLines:     4,
+			AuthoredRatio:     4.0 / 15.0,
+			UnmaintainedFiles: []string{"pkg/a.go"},
+		},
+		{
+			Owner:             "@org/backend",
+			DeclaredLines:     5,
+			AuthoredLines:     5,
+			AuthoredRatio:     1,
+			UnmaintainedFiles: []string{},
+		},
+	}, diffs)
+}
@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"sort"
+)
+
+const (
+	// PaletteRank colours areas by the rank of their authors on a gradient, without colours of authors.
+	PaletteRank = "rank"
+	// PaletteCategorical gives each author a distinct colour.
+	PaletteCategorical = "categorical"
+	// PaletteColorBlind gives each author a colour distinguishable with colour vision deficiencies (Okabe-Ito).
+	PaletteColorBlind = "colorblind"
+)
+
+// Palettes are the colours authors are coloured with. PaletteRank has none.
+var Palettes = map[string][]string{
+	PaletteRank: {},
+	PaletteCategorical: {
+		"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+		"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+		"#a0cbe8", "#ffbe7d", "#ff9d9a", "#86bcb6", "#8cd17d",
+		"#f1ce63", "#d4a6c8", "#fabfd2", "#d7b5a6", "#79706e",
+	},
+	PaletteColorBlind: {
+		"#e69f00", "#56b4e9", "#009e73", "#f0e442",
+		"#0072b2", "#d55e00", "#cc79a7", "#999999",
+	},
+}
+
+var colorRegex = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+
+// LoadColors reads colours of authors from path. The file is a json object of author email, or team name, to "#rrggbb".
+func LoadColors(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var colors map[string]string
+	err = json.Unmarshal(data, &colors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid colors: path=%v, err=%w", path, err)
+	}
+
+	err = ValidateColors(colors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid colors: path=%v, err=%w", path, err)
+	}
+
+	return colors, nil
+}
+
+// ValidateColors returns an error if a colour of colors is not "#rrggbb".
+// Colours are written into html and svg as they are, so they must be checked wherever they come from.
+func ValidateColors(colors map[string]string) error {
+	for author, color := range colors {
+		if !colorRegex.MatchString(color) {
+			return fmt.Errorf("invalid color: author=%v, color=%v", author, color)
+		}
+	}
+	return nil
+}
+
+// ColorAuthors sets a colour of palette to each author of generateResult, unless colors gives the author one.
+// An author starts from the colour picked by the hash of the email, and takes the next one while it is used by a higher ranked author,
+// so that authors keep their colours over the commits and mostly over the runs.
+// Colours are repeated only after all colours of the palette are used.
+func ColorAuthors(generateResult *GenerateResult, palette string, colors map[string]string) error {
+	paletteColors, ok := Palettes[palette]
+	if !ok {
+		return fmt.Errorf("unknown palette: palette=%v", palette)
+	}
+
+	if palette == PaletteRank && len(colors) > 0 {
+		return fmt.Errorf("colors cannot be set to the palette: palette=%v", palette)
+	}
+
+	err := ValidateColors(colors)
+	if err != nil {
+		return err
+	}
+
+	generateResult.Palette = palette
+	generateResult.Colors = nil
+	if palette == PaletteRank {
+		return nil
+	}
+
+	bestRanks := map[string]int{}
+	for _, commit := range generateResult.Commits {
+		for _, lineCount := range commit.LineCounts {
+			for _, author := range lineCount.Authors {
+				if rank, ok := bestRanks[author.Email]; !ok || author.Rank < rank {
+					bestRanks[author.Email] = author.Rank
+				}
+			}
+		}
+	}
+
+	authors := make([]string, 0, len(bestRanks))
+	for author := range bestRanks {
+		authors = append(authors, author)
+	}
+	sort.SliceStable(authors, func(i, j int) bool {
+		if bestRanks[authors[i]] == bestRanks[authors[j]] {
+			return authors[i] < authors[j]
+		}
+		return bestRanks[authors[i]] < bestRanks[authors[j]]
+	})
+
+	generateResult.Colors = map[string]string{}
+	used := map[string]bool{}
+	for _, author := range authors {
+		if color, ok := colors[author]; ok {
+			generateResult.Colors[author] = color
+			used[color] = true
+		}
+	}
+
+	for _, author := range authors {
+		if _, ok := generateResult.Colors[author]; ok {
+			continue
+		}
+
+		start := colorIndexOf(author, len(paletteColors))
+		color := paletteColors[start]
+		for i := 0; i < len(paletteColors); i++ {
+			candidate := paletteColors[(start+i)%len(paletteColors)]
+			if !used[candidate] {
+				color = candidate
+				break
+			}
+		}
+
+		generateResult.Colors[author] = color
+		used[color] = true
+	}
+
+	return nil
+}
+
+func colorIndexOf(author string, paletteSize int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(author))
+	return int(hash.Sum32() % uint32(paletteSize))
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorAuthors(t *testing.T) {
+	generateResult := newTestExportResult()
+	err := ColorAuthors(generateResult, PaletteCategorical, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, PaletteCategorical, generateResult.Palette)
+	assert.Equal(t, 3, len(generateResult.Colors))
+
+	found := map[string]bool{}
+	for _, color := range generateResult.Colors {
+		assert.Contains(t, Palettes[PaletteCategorical], color)
+		assert.False(t, found[color], color)
+		found[color] = true
+	}
+
+	// the top ranked author takes the colour picked by the hash of the email
+	alice := "alice@example.com"
+	assert.Equal(t, Palettes[PaletteCategorical][colorIndexOf(alice, len(Palettes[PaletteCategorical]))], generateResult.Colors[alice])
+
+	// authors keep their colours in another run with other authors ranked below them
+	anotherResult := newTestExportResult()
+	anotherResult.Commits[0].LineCounts[0].Authors = anotherResult.Commits[0].LineCounts[0].Authors[:1]
+	err = ColorAuthors(anotherResult, PaletteCategorical, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, generateResult.Colors[alice], anotherResult.Colors[alice])
+
+	err = ColorAuthors(generateResult, PaletteColorBlind, map[string]string{"bob@example.com": "#123456"})
+	assert.NoError(t, err)
+	assert.Equal(t, "#123456", generateResult.Colors["bob@example.com"])
+	assert.Contains(t, Palettes[PaletteColorBlind], generateResult.Colors[alice])
+
+	err = ColorAuthors(generateResult, PaletteRank, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, PaletteRank, generateResult.Palette)
+	assert.Nil(t, generateResult.Colors)
+
+	assert.Error(t, ColorAuthors(generateResult, PaletteRank, map[string]string{alice: "#123456"}))
+	assert.Error(t, ColorAuthors(generateResult, "unknown", nil))
+	assert.Error(t, ColorAuthors(generateResult, PaletteCategorical, map[string]string{alice: "#000000\" onload=\"alert(1)"}))
+}
+
+func TestColorAuthors__repeat(t *testing.T) {
+	generateResult := newTestExportResult()
+	authors := make([]GenerateResultCommitLineCountAuthor, 0)
+	for i := 0; i < len(Palettes[PaletteColorBlind])+2; i++ {
+		authors = append(authors, GenerateResultCommitLineCountAuthor{Email: fmt.Sprintf("author%v@example.com", i), Rank: i + 1})
+	}
+	generateResult.Commits[0].LineCounts[0].Authors = authors
+
+	err := ColorAuthors(generateResult, PaletteColorBlind, nil)
+	assert.NoError(t, err)
+
+	found := map[string]bool{}
+	for _, author := range authors[:len(Palettes[PaletteColorBlind])] {
+		color := generateResult.Colors[author.Email]
+		assert.False(t, found[color], color)
+		found[color] = true
+	}
+	assert.Equal(t, len(authors), len(generateResult.Colors))
+}
+
+func TestLoadColors(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "colors.json")
+	err := os.WriteFile(path, []byte(`{"alice@example.com": "#FF0000", "@org/backend": "#00ff00"}`), 0644)
+	assert.NoError(t, err)
+
+	colors, err := LoadColors(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"alice@example.com": "#FF0000", "@org/backend": "#00ff00"}, colors)
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	err = os.WriteFile(invalidPath, []byte(`{"alice@example.com": "red"}`), 0644)
+	assert.NoError(t, err)
+
+	_, err = LoadColors(invalidPath)
+	assert.Error(t, err)
+
+	_, err = LoadColors(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
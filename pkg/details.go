@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"math"
+	"sort"
+)
+
+// GenerateResultFile is the number of lines of a file or a directory by author.
+type GenerateResultFile struct {
+	Path    string         `json:"path"`
+	Lines   int            `json:"lines"`
+	Authors map[string]int `json:"authors"`
+}
+
+// newGenerateResultFiles lists matched files of result, and directories rolling them up, in path order.
+func newGenerateResultFiles(result *CountLinesResult) ([]GenerateResultFile, []GenerateResultFile) {
+	files := make([]GenerateResultFile, 0, len(result.LinesByFile))
+	linesByDirectory := map[string]map[string]int{}
+	for file, linesByAuthor := range result.LinesByFile {
+		files = append(files, newGenerateResultFile(file, linesByAuthor))
+
+		for _, directory := range parentDirectories(file, math.MaxInt) {
+			if linesByDirectory[directory] == nil {
+				linesByDirectory[directory] = map[string]int{}
+			}
+			for author, lines := range linesByAuthor {
+				linesByDirectory[directory][author] += lines
+			}
+		}
+	}
+
+	directories := make([]GenerateResultFile, 0, len(linesByDirectory))
+	for directory, linesByAuthor := range linesByDirectory {
+		directories = append(directories, newGenerateResultFile(directory, linesByAuthor))
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	sort.SliceStable(directories, func(i, j int) bool {
+		return directories[i].Path < directories[j].Path
+	})
+
+	return files, directories
+}
+
+func newGenerateResultFile(path string, linesByAuthor map[string]int) GenerateResultFile {
+	file := GenerateResultFile{
+		Path:    path,
+		Authors: map[string]int{},
+	}
+	for author, lines := range linesByAuthor {
+		file.Lines += lines
+		file.Authors[author] = lines
+	}
+	return file
+}
@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewGenerateResultFiles(t *testing.T) {
+	result := &CountLinesResult{
+		LinesByFile: map[string]map[string]int{
+			"main.go":      {"alice@example.com": 10},
+			"pkg/a.go":     {"alice@example.com": 3, "bob@example.com": 5},
+			"pkg/sub/b.go": {"bob@example.com": 2},
+		},
+	}
+
+	files, directories := newGenerateResultFiles(result)
+	assert.Equal(t, []GenerateResultFile{
+		{Path: "main.go", Lines: 10, Authors: map[string]int{"alice@example.com": 10}},
+		{Path: "pkg/a.go", Lines: 8, Authors: map[string]int{"alice@example.com": 3, "bob@example.com": 5}},
+		{Path: "pkg/sub/b.go", Lines: 2, Authors: map[string]int{"bob@example.com": 2}},
+	}, files)
+	assert.Equal(t, []GenerateResultFile{
+		{Path: ".", Lines: 20, Authors: map[string]int{"alice@example.com": 13, "bob@example.com": 7}},
+		{Path: "pkg", Lines: 10, Authors: map[string]int{"alice@example.com": 3, "bob@example.com": 7}},
+		{Path: "pkg/sub", Lines: 2, Authors: map[string]int{"bob@example.com": 2}},
+	}, directories)
+}
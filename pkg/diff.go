@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ResultDiffRevision struct {
+	Repository  string    `json:"repository"`
+	Hash        string    `json:"hash"`
+	CommittedAt time.Time `json:"committedAt"`
+}
+
+type ResultDiffAuthor struct {
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	GitHubLogin string `json:"gitHubLogin"`
+	// OldRank and NewRank are 0 if the author is not counted in the result.
+	OldRank int `json:"oldRank"`
+	NewRank int `json:"newRank"`
+	// RankChange is positive if the author ranks up. It is 0 if the author is missing in either result.
+	RankChange  int      `json:"rankChange"`
+	OldLines    int      `json:"oldLines"`
+	NewLines    int      `json:"newLines"`
+	LineDelta   int      `json:"lineDelta"`
+	GainedAreas []string `json:"gainedAreas"`
+	LostAreas   []string `json:"lostAreas"`
+}
+
+type ResultDiffFilter struct {
+	FilterRegex string             `json:"filterRegex"`
+	Authors     []ResultDiffAuthor `json:"authors"`
+}
+
+type ResultDiff struct {
+	Old     ResultDiffRevision `json:"old"`
+	New     ResultDiffRevision `json:"new"`
+	Filters []ResultDiffFilter `json:"filters"`
+}
+
+// DiffGenerateResults compares the latest commits of oldResult and newResult filter by filter.
+func DiffGenerateResults(oldResult *GenerateResult, newResult *GenerateResult) (*ResultDiff, error) {
+	if oldResult == nil || newResult == nil {
+		return nil, errors.New("generate result is nil")
+	}
+
+	oldCommit, newCommit := GenerateResultCommit{}, GenerateResultCommit{}
+	if len(oldResult.Commits) > 0 {
+		oldCommit = oldResult.Commits[0]
+	}
+	if len(newResult.Commits) > 0 {
+		newCommit = newResult.Commits[0]
+	}
+
+	diff := &ResultDiff{
+		Old: ResultDiffRevision{
+			Repository:  oldResult.Repository,
+			Hash:        oldCommit.Hash,
+			CommittedAt: oldCommit.CommittedAt,
+		},
+		New: ResultDiffRevision{
+			Repository:  newResult.Repository,
+			Hash:        newCommit.Hash,
+			CommittedAt: newCommit.CommittedAt,
+		},
+		Filters: make([]ResultDiffFilter, 0),
+	}
+
+	filters := make([]string, 0)
+	oldLineCounts, newLineCounts := map[string]GenerateResultCommitLineCount{}, map[string]GenerateResultCommitLineCount{}
+	for _, lineCount := range newCommit.LineCounts {
+		filters = append(filters, lineCount.FilterRegex)
+		newLineCounts[lineCount.FilterRegex] = lineCount
+	}
+	for _, lineCount := range oldCommit.LineCounts {
+		if _, ok := newLineCounts[lineCount.FilterRegex]; !ok {
+			filters = append(filters, lineCount.FilterRegex)
+		}
+		oldLineCounts[lineCount.FilterRegex] = lineCount
+	}
+
+	for _, filter := range filters {
+		diff.Filters = append(diff.Filters, ResultDiffFilter{
+			FilterRegex: filter,
+			Authors:     diffAuthors(oldLineCounts[filter], newLineCounts[filter]),
+		})
+	}
+
+	return diff, nil
+}
+
+func diffAuthors(oldLineCount GenerateResultCommitLineCount, newLineCount GenerateResultCommitLineCount) []ResultDiffAuthor {
+	oldAreas, newAreas := areasByAuthor(oldLineCount), areasByAuthor(newLineCount)
+
+	authorByEmail := map[string]*ResultDiffAuthor{}
+	authorOf := func(author GenerateResultCommitLineCountAuthor) *ResultDiffAuthor {
+		diffAuthor, ok := authorByEmail[author.Email]
+		if !ok {
+			diffAuthor = &ResultDiffAuthor{
+				Email:       author.Email,
+				Name:        author.Name,
+				GitHubLogin: author.GitHubLogin,
+			}
+			authorByEmail[author.Email] = diffAuthor
+		}
+		return diffAuthor
+	}
+	for _, author := range newLineCount.Authors {
+		diffAuthor := authorOf(author)
+		diffAuthor.NewRank = author.Rank
+		diffAuthor.NewLines = author.LineCount
+	}
+	for _, author := range oldLineCount.Authors {
+		diffAuthor := authorOf(author)
+		diffAuthor.OldRank = author.Rank
+		diffAuthor.OldLines = author.LineCount
+	}
+
+	authors := make([]ResultDiffAuthor, 0, len(authorByEmail))
+	for email, author := range authorByEmail {
+		if author.OldRank > 0 && author.NewRank > 0 {
+			author.RankChange = author.OldRank - author.NewRank
+		}
+		author.LineDelta = author.NewLines - author.OldLines
+		author.GainedAreas = subtractAreas(newAreas[email], oldAreas[email])
+		author.LostAreas = subtractAreas(oldAreas[email], newAreas[email])
+		authors = append(authors, *author)
+	}
+
+	// authors of the new result in rank order, then the authors who are gone
+	sort.SliceStable(authors, func(i, j int) bool {
+		if (authors[i].NewRank == 0) != (authors[j].NewRank == 0) {
+			return authors[i].NewRank != 0
+		} else if authors[i].NewRank != authors[j].NewRank {
+			return authors[i].NewRank < authors[j].NewRank
+		} else if authors[i].OldRank != authors[j].OldRank {
+			return authors[i].OldRank < authors[j].OldRank
+		}
+		return authors[i].Email < authors[j].Email
+	})
+
+	return authors
+}
+
+func areasByAuthor(lineCount GenerateResultCommitLineCount) map[string][]string {
+	areas := map[string][]string{}
+	for _, area := range lineCount.Areas {
+		areas[area.AuthorEmail] = append(areas[area.AuthorEmail], area.Name)
+	}
+	return areas
+}
+
+// subtractAreas returns areas in a but not in b, keeping the order of a.
+func subtractAreas(a []string, b []string) []string {
+	found := map[string]bool{}
+	for _, area := range b {
+		found[area] = true
+	}
+
+	areas := make([]string, 0)
+	for _, area := range a {
+		if !found[area] {
+			areas = append(areas, area)
+		}
+	}
+	return areas
+}
+
+// RenderResultDiffMarkdown renders diff as Markdown tables, one for each filter.
+func RenderResultDiffMarkdown(diff *ResultDiff) (string, error) {
+	if diff == nil {
+		return "", errors.New("diff is nil")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("# Kunitori diff\n\n")
+	builder.WriteString(fmt.Sprintf("- Old: %v %v (%v)\n", diff.Old.Repository, diff.Old.Hash, diff.Old.CommittedAt.Format(time.RFC3339)))
+	builder.WriteString(fmt.Sprintf("- New: %v %v (%v)\n", diff.New.Repository, diff.New.Hash, diff.New.CommittedAt.Format(time.RFC3339)))
+
+	for _, filter := range diff.Filters {
+		builder.WriteString(fmt.Sprintf("\n## `%v`\n\n", escapeMarkdownCode(filter.FilterRegex)))
+		builder.WriteString("| Author | Rank | Lines | Gained | Lost |\n")
+		builder.WriteString("| --- | ---: | ---: | --- | --- |\n")
+		for _, author := range filter.Authors {
+			builder.WriteString(fmt.Sprintf(
+				"| %v | %v | %v | %v | %v |\n",
+				escapeMarkdownTableCell(markdownAuthorName(GenerateResultCommitLineCountAuthor{
+					Email:       author.Email,
+					Name:        author.Name,
+					GitHubLogin: author.GitHubLogin,
+				})),
+				formatRankChange(author),
+				fmt.Sprintf("%v (%+d)", author.NewLines, author.LineDelta),
+				escapeMarkdownTableCell(strings.Join(author.GainedAreas, ", ")),
+				escapeMarkdownTableCell(strings.Join(author.LostAreas, ", ")),
+			))
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func formatRankChange(author ResultDiffAuthor) string {
+	switch {
+	case author.OldRank == 0:
+		return fmt.Sprintf("%v (new)", author.NewRank)
+	case author.NewRank == 0:
+		return fmt.Sprintf("- (was %v)", author.OldRank)
+	case author.RankChange > 0:
+		return fmt.Sprintf("%v (▲%v)", author.NewRank, author.RankChange)
+	case author.RankChange < 0:
+		return fmt.Sprintf("%v (▼%v)", author.NewRank, -author.RankChange)
+	}
+	return fmt.Sprintf("%v (-)", author.NewRank)
+}
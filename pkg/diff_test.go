@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestDiffResults() (*GenerateResult, *GenerateResult) {
+	oldResult := newTestExportResult()
+
+	newResult := newTestExportResult()
+	newResult.Commits[0].Hash = "3fa8fa83724e394a098890c40cc324fa90b080b5"
+	newResult.Commits[0].CommittedAt = time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	newResult.Commits[0].LineCounts[0].Areas = []GenerateResultCommitLineCountArea{
+		{Name: "Area30", Size: 30, Ratio: 0.5, AuthorEmail: "bob@example.com", AuthorRank: 1},
+		{Name: "Area20", Size: 20, Ratio: 0.333, AuthorEmail: "alice@example.com", AuthorRank: 2},
+		{Name: "Area10", Size: 10, Ratio: 0.167, AuthorEmail: "dave@example.com", AuthorRank: 3},
+	}
+	newResult.Commits[0].LineCounts[0].Authors = []GenerateResultCommitLineCountAuthor{
+		{Email: "bob@example.com", Name: "Bob, Jr.", LineCount: 70, Rank: 1},
+		{Email: "alice@example.com", Name: "Alice", GitHubLogin: "alice", LineCount: 50, Rank: 2},
+		{Email: "dave@example.com", LineCount: 20, Rank: 3},
+	}
+	newResult.Commits[0].LineCounts = append(newResult.Commits[0].LineCounts, GenerateResultCommitLineCount{
+		FilterRegex: "\\.md$",
+		Areas:       []GenerateResultCommitLineCountArea{},
+		Authors: []GenerateResultCommitLineCountAuthor{
+			{Email: "alice@example.com", LineCount: 5, Rank: 1},
+		},
+	})
+
+	return oldResult, newResult
+}
+
+func TestDiffGenerateResults(t *testing.T) {
+	oldResult, newResult := newTestDiffResults()
+
+	diff, err := DiffGenerateResults(oldResult, newResult)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "2fa8fa83724e394a098890c40cc324fa90b080b5", diff.Old.Hash)
+	assert.Equal(t, "3fa8fa83724e394a098890c40cc324fa90b080b5", diff.New.Hash)
+	assert.Equal(t, []ResultDiffFilter{
+		{
+			FilterRegex: "\\.go$",
+			Authors: []ResultDiffAuthor{
+				{
+					Email: "bob@example.com", Name: "Bob, Jr.",
+					OldRank: 2, NewRank: 1, RankChange: 1,
+					OldLines: 30, NewLines: 70, LineDelta: 40,
+					GainedAreas: []string{"Area30"}, LostAreas: []string{"Area20"},
+				},
+				{
+					Email: "alice@example.com", Name: "Alice", GitHubLogin: "alice",
+					OldRank: 1, NewRank: 2, RankChange: -1,
+					OldLines: 60, NewLines: 50, LineDelta: -10,
+					GainedAreas: []string{"Area20"}, LostAreas: []string{"Area30"},
+				},
+				{
+					Email:   "dave@example.com",
+					OldRank: 0, NewRank: 3, RankChange: 0,
+					OldLines: 0, NewLines: 20, LineDelta: 20,
+					GainedAreas: []string{"Area10"}, LostAreas: []string{},
+				},
+				{
+					Email:   "carol@example.com",
+					OldRank: 3, NewRank: 0, RankChange: 0,
+					OldLines: 10, NewLines: 0, LineDelta: -10,
+					GainedAreas: []string{}, LostAreas: []string{},
+				},
+			},
+		},
+		{
+			FilterRegex: "\\.md$",
+			Authors: []ResultDiffAuthor{
+				{
+					Email:   "alice@example.com",
+					OldRank: 0, NewRank: 1,
+					NewLines: 5, LineDelta: 5,
+					GainedAreas: []string{}, LostAreas: []string{},
+				},
+			},
+		},
+	}, diff.Filters)
+
+	_, err = DiffGenerateResults(nil, newResult)
+	assert.Error(t, err)
+}
+
+func TestRenderResultDiffMarkdown(t *testing.T) {
+	oldResult, newResult := newTestDiffResults()
+	newResult.Commits[0].LineCounts = newResult.Commits[0].LineCounts[:1]
+
+	diff, err := DiffGenerateResults(oldResult, newResult)
+	assert.NoError(t, err)
+
+	markdown, err := RenderResultDiffMarkdown(diff)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Kunitori diff\n"+
+		"\n"+
+		"- Old: https://github.com/yktakaha4/kunitori 2fa8fa83724e394a098890c40cc324fa90b080b5 (2023-01-01T00:00:00Z)\n"+
+		"- New: https://github.com/yktakaha4/kunitori 3fa8fa83724e394a098890c40cc324fa90b080b5 (2023-02-01T00:00:00Z)\n"+
+		"\n"+
+		"## `\\.go$`\n"+
+		"\n"+
+		"| Author | Rank | Lines | Gained | Lost |\n"+
+		"| --- | ---: | ---: | --- | --- |\n"+
+		"| Bob, Jr. | 1 (▲1) | 70 (+40) | Area30 | Area20 |\n"+
+		"| @alice | 2 (▼1) | 50 (-10) | Area20 | Area30 |\n"+
+		"| dave@example.com | 3 (new) | 20 (+20) | Area10 |  |\n"+
+		"| carol@example.com | - (was 3) | 0 (-10) |  |  |\n",
+		markdown,
+	)
+}
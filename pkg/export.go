@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RenderAuthorsCsv renders one row for each author of each filter of each commit.
+func RenderAuthorsCsv(generateResult *GenerateResult) (string, error) {
+	if generateResult == nil {
+		return "", errors.New("generate result is nil")
+	}
+
+	records := [][]string{
+		{"commit_hash", "committed_at", "filter_regex", "rank", "email", "name", "github_login", "lines", "share"},
+	}
+	for _, commit := range generateResult.Commits {
+		for _, lineCount := range commit.LineCounts {
+			totalWeight := totalWeightOf(generateResult, lineCount)
+			for _, author := range lineCount.Authors {
+				records = append(records, []string{
+					commit.Hash,
+					commit.CommittedAt.Format(time.RFC3339),
+					lineCount.FilterRegex,
+					strconv.Itoa(author.Rank),
+					author.Email,
+					author.Name,
+					author.GitHubLogin,
+					strconv.Itoa(author.LineCount),
+					formatShare(authorWeightOf(generateResult, author), totalWeight),
+				})
+			}
+		}
+	}
+
+	return renderCsv(records)
+}
+
+// RenderAreasCsv renders one row for each area of each filter of each commit.
+func RenderAreasCsv(generateResult *GenerateResult) (string, error) {
+	if generateResult == nil {
+		return "", errors.New("generate result is nil")
+	}
+
+	records := [][]string{
+		{"commit_hash", "committed_at", "filter_regex", "area", "size", "ratio", "author_email", "author_rank"},
+	}
+	for _, commit := range generateResult.Commits {
+		for _, lineCount := range commit.LineCounts {
+			for _, area := range lineCount.Areas {
+				records = append(records, []string{
+					commit.Hash,
+					commit.CommittedAt.Format(time.RFC3339),
+					lineCount.FilterRegex,
+					area.Name,
+					strconv.FormatFloat(area.Size, 'f', -1, 64),
+					strconv.FormatFloat(area.Ratio, 'f', -1, 64),
+					area.AuthorEmail,
+					strconv.Itoa(area.AuthorRank),
+				})
+			}
+		}
+	}
+
+	return renderCsv(records)
+}
+
+func renderCsv(records [][]string) (string, error) {
+	buf := bytes.NewBufferString("")
+	writer := csv.NewWriter(buf)
+	err := writer.WriteAll(records)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderMarkdown renders a leaderboard of the latest commit for each filter, with the areas each author owns.
+func RenderMarkdown(generateResult *GenerateResult) (string, error) {
+	if generateResult == nil {
+		return "", errors.New("generate result is nil")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("# Kunitori\n\n")
+	builder.WriteString(fmt.Sprintf("- Repository: %v\n", generateResult.Repository))
+
+	if len(generateResult.Commits) == 0 {
+		builder.WriteString("\nNo commits found.\n")
+		return builder.String(), nil
+	}
+
+	commit := generateResult.Commits[0]
+	builder.WriteString(fmt.Sprintf("- Revision: %v (%v)\n", commit.Hash, commit.CommittedAt.Format(time.RFC3339)))
+
+	for _, lineCount := range commit.LineCounts {
+		builder.WriteString(fmt.Sprintf("\n## `%v`\n\n", escapeMarkdownCode(lineCount.FilterRegex)))
+
+		totalWeight := totalWeightOf(generateResult, lineCount)
+		builder.WriteString("| Rank | Author | Lines | Share | Areas |\n")
+		builder.WriteString("| ---: | --- | ---: | ---: | ---: |\n")
+
+		areasByAuthor := map[string][]string{}
+		for _, area := range lineCount.Areas {
+			areasByAuthor[area.AuthorEmail] = append(areasByAuthor[area.AuthorEmail], area.Name)
+		}
+
+		for _, author := range lineCount.Authors {
+			builder.WriteString(fmt.Sprintf(
+				"| %v | %v | %v | %v | %v |\n",
+				author.Rank,
+				escapeMarkdownTableCell(markdownAuthorName(author)),
+				author.LineCount,
+				formatPercent(shareOf(authorWeightOf(generateResult, author), totalWeight)),
+				len(areasByAuthor[author.Email]),
+			))
+		}
+
+		builder.WriteString("\n")
+		for _, author := range lineCount.Authors {
+			areas := areasByAuthor[author.Email]
+			if len(areas) == 0 {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("- %v: %v\n", markdownAuthorName(author), strings.Join(areas, ", ")))
+		}
+	}
+
+	return builder.String(), nil
+}
+
+func markdownAuthorName(author GenerateResultCommitLineCountAuthor) string {
+	if author.GitHubLogin != "" {
+		return "@" + author.GitHubLogin
+	} else if author.Name != "" {
+		return author.Name
+	}
+	return author.Email
+}
+
+// authorWeightOf returns the score of author if lines are weighted, otherwise the lines, as the chart and the allocation do.
+func authorWeightOf(generateResult *GenerateResult, author GenerateResultCommitLineCountAuthor) float64 {
+	if generateResult.Weighting.Method == WeightingRecency {
+		return author.Score
+	}
+	return float64(author.LineCount)
+}
+
+func totalWeightOf(generateResult *GenerateResult, lineCount GenerateResultCommitLineCount) float64 {
+	totalWeight := float64(0)
+	for _, author := range lineCount.Authors {
+		totalWeight += authorWeightOf(generateResult, author)
+	}
+	return totalWeight
+}
+
+func shareOf(weight float64, totalWeight float64) float64 {
+	if totalWeight == 0 {
+		return 0
+	}
+	return weight / totalWeight
+}
+
+func formatShare(weight float64, totalWeight float64) string {
+	return strconv.FormatFloat(shareOf(weight, totalWeight), 'f', 4, 64)
+}
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestExportResult() *GenerateResult {
+	return &GenerateResult{
+		SchemaVersion: GenerateResultSchemaVersion,
+		Repository:    "https://github.com/yktakaha4/kunitori",
+		Source:        "github",
+		GitHubUrl:     "https://github.com",
+		Region:        "__TEST",
+		Allocation:    AllocationGreedy,
+		GeneratedAt:   time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Commits: []GenerateResultCommit{
+			{
+				Hash:        "2fa8fa83724e394a098890c40cc324fa90b080b5",
+				CommittedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				LineCounts: []GenerateResultCommitLineCount{
+					{
+						FilterRegex: "\\.go$",
+						FileCount:   2,
+						Areas: []GenerateResultCommitLineCountArea{
+							{Name: "Area30", Size: 30, Ratio: 0.5, AuthorEmail: "alice@example.com", AuthorRank: 1},
+							{Name: "Area20", Size: 20, Ratio: 0.333, AuthorEmail: "bob@example.com", AuthorRank: 2},
+						},
+						Authors: []GenerateResultCommitLineCountAuthor{
+							{Email: "alice@example.com", Name: "Alice", GitHubLogin: "alice", LineCount: 60, Rank: 1},
+							{Email: "bob@example.com", Name: "Bob, Jr.", LineCount: 30, Rank: 2},
+							{Email: "carol@example.com", LineCount: 10, Rank: 3},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRenderAuthorsCsv(t *testing.T) {
+	csv, err := RenderAuthorsCsv(newTestExportResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `commit_hash,committed_at,filter_regex,rank,email,name,github_login,lines,share
+2fa8fa83724e394a098890c40cc324fa90b080b5,2023-01-01T00:00:00Z,\.go$,1,alice@example.com,Alice,alice,60,0.6000
+2fa8fa83724e394a098890c40cc324fa90b080b5,2023-01-01T00:00:00Z,\.go$,2,bob@example.com,"Bob, Jr.",,30,0.3000
+2fa8fa83724e394a098890c40cc324fa90b080b5,2023-01-01T00:00:00Z,\.go$,3,carol@example.com,,,10,0.1000
+`, csv)
+
+	t.Run("weighted", func(t *testing.T) {
+		csv, err := RenderAuthorsCsv(newTestWeightedExportResult())
+		assert.NoError(t, err)
+		assert.Contains(t, csv, ",alice@example.com,Alice,alice,60,0.5000\n")
+		assert.Contains(t, csv, ",bob@example.com,\"Bob, Jr.\",,30,0.4000\n")
+		assert.Contains(t, csv, ",carol@example.com,,,10,0.1000\n")
+	})
+}
+
+// newTestWeightedExportResult weights the lines of newTestExportResult by recency, so that shares differ from lines.
+func newTestWeightedExportResult() *GenerateResult {
+	generateResult := newTestExportResult()
+	generateResult.Weighting = GenerateResultWeighting{Method: WeightingRecency, HalfLifeDays: 30}
+	scores := map[string]float64{"alice@example.com": 25, "bob@example.com": 20, "carol@example.com": 5}
+	authors := generateResult.Commits[0].LineCounts[0].Authors
+	for i := range authors {
+		authors[i].Score = scores[authors[i].Email]
+	}
+	return generateResult
+}
+
+func TestRenderAreasCsv(t *testing.T) {
+	csv, err := RenderAreasCsv(newTestExportResult())
+	assert.NoError(t, err)
+	assert.Equal(t, `commit_hash,committed_at,filter_regex,area,size,ratio,author_email,author_rank
+2fa8fa83724e394a098890c40cc324fa90b080b5,2023-01-01T00:00:00Z,\.go$,Area30,30,0.5,alice@example.com,1
+2fa8fa83724e394a098890c40cc324fa90b080b5,2023-01-01T00:00:00Z,\.go$,Area20,20,0.333,bob@example.com,2
+`, csv)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	markdown, err := RenderMarkdown(newTestExportResult())
+	assert.NoError(t, err)
+	assert.Equal(t, "# Kunitori\n"+
+		"\n"+
+		"- Repository: https://github.com/yktakaha4/kunitori\n"+
+		"- Revision: 2fa8fa83724e394a098890c40cc324fa90b080b5 (2023-01-01T00:00:00Z)\n"+
+		"\n"+
+		"## `\\.go$`\n"+
+		"\n"+
+		"| Rank | Author | Lines | Share | Areas |\n"+
+		"| ---: | --- | ---: | ---: | ---: |\n"+
+		"| 1 | @alice | 60 | 60.0% | 1 |\n"+
+		"| 2 | Bob, Jr. | 30 | 30.0% | 1 |\n"+
+		"| 3 | carol@example.com | 10 | 10.0% | 0 |\n"+
+		"\n"+
+		"- @alice: Area30\n"+
+		"- Bob, Jr.: Area20\n", markdown)
+
+	t.Run("weighted", func(t *testing.T) {
+		markdown, err := RenderMarkdown(newTestWeightedExportResult())
+		assert.NoError(t, err)
+		assert.Contains(t, markdown, "| 1 | @alice | 60 | 50.0% | 1 |\n")
+		assert.Contains(t, markdown, "| 2 | Bob, Jr. | 30 | 40.0% | 1 |\n")
+		assert.Contains(t, markdown, "| 3 | carol@example.com | 10 | 10.0% | 0 |\n")
+	})
+
+	t.Run("no commits", func(t *testing.T) {
+		markdown, err := RenderMarkdown(&GenerateResult{Repository: "dummy", Commits: []GenerateResultCommit{}})
+		assert.NoError(t, err)
+		assert.Equal(t, "# Kunitori\n\n- Repository: dummy\n\nNo commits found.\n", markdown)
+	})
+}
@@ -1,23 +1,50 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"github.com/go-git/go-git/v5"
-	"log"
-	"os"
-	"path/filepath"
+	"net/url"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 )
 
 type GenerateOptions struct {
-	RepositoryUrl        string
-	RepositoryPath       string
-	Region               string
+	RepositoryUrl  string
+	RepositoryPath string
+	Region         string
+	// Allocation is the name of the allocation strategy passed to GetAllocator.
+	Allocation string
+	// Allocator overrides the strategy named by Allocation if it is set.
+	Allocator            Allocator
 	SearchCommitsOptions *SearchCommitsOptions
 	CountLinesOption     *CountLinesOption
+	LoginOptions         *LoginOptions
+	// GroupBy is GroupByAuthor or GroupByTeam. Areas are allocated to authors if it is empty.
+	GroupBy string
+	// Teams defines the members of each team. It is required when GroupBy is GroupByTeam.
+	// Teams named like CODEOWNERS teams (e.g. "@org/team") are also used to match owners for OwnershipDiff.
+	Teams Teams
+	// Ownership is OwnershipBlame or OwnershipCodeOwners. Lines are attributed by blame if it is empty.
+	Ownership string
+	// OwnershipDiff compares CODEOWNERS with blame when Ownership is OwnershipCodeOwners.
+	OwnershipDiff bool
+	// Details adds the lines of each matched file and directory by author to the result.
+	Details bool
+	// Survival counts how many lines added between the sampled commits survive at the later ones.
+	Survival bool
+	// Palette colours the authors. Areas are coloured by rank without colours of authors if it is empty.
+	Palette string
+	// Colors overrides the colours of the palette by author email or team name.
+	Colors map[string]string
+	// Lang is the language the chart is shown in. The chart is shown in LangEn if it is empty.
+	Lang string
+	// Progress is called as Generate proceeds. It is called on the goroutine running Generate.
+	Progress func(event ProgressEvent)
+	// Logger receives messages of Generate. Messages are discarded if it is nil.
+	Logger Logger
 }
 
 type GenerateResultCommitLineCountAuthor struct {
@@ -25,7 +52,11 @@ type GenerateResultCommitLineCountAuthor struct {
 	Name        string `json:"name"`
 	GitHubLogin string `json:"gitHubLogin"`
 	LineCount   int    `json:"lineCount"`
-	Rank        int    `json:"rank"`
+	// Score is the sum of the recency weights of lines. It is set only when lines are weighted.
+	Score float64 `json:"score,omitempty"`
+	Rank  int     `json:"rank"`
+	// Members is the breakdown of a team by its authors. It is set only when grouped by team.
+	Members []GenerateResultCommitLineCountAuthor `json:"members,omitempty"`
 }
 
 type GenerateResultCommitLineCountArea struct {
@@ -41,6 +72,11 @@ type GenerateResultCommitLineCount struct {
 	FileCount   int                                   `json:"fileCount"`
 	Areas       []GenerateResultCommitLineCountArea   `json:"areas"`
 	Authors     []GenerateResultCommitLineCountAuthor `json:"authors"`
+	// Files and Directories are set only when Details is requested.
+	Files       []GenerateResultFile `json:"files,omitempty"`
+	Directories []GenerateResultFile `json:"directories,omitempty"`
+	// OwnershipDiffs is set only when OwnershipDiff is requested.
+	OwnershipDiffs []OwnershipDiff `json:"ownershipDiffs,omitempty"`
 }
 
 type GenerateResultCommit struct {
@@ -49,222 +85,384 @@ type GenerateResultCommit struct {
 	LineCounts  []GenerateResultCommitLineCount `json:"lineCounts"`
 }
 
+const (
+	WeightingNone    = "none"
+	WeightingRecency = "recency"
+)
+
+// GenerateResultWeighting describes how lines are weighted before allocating areas.
+type GenerateResultWeighting struct {
+	Method string `json:"method"`
+	// HalfLifeDays is the half-life of the recency weight in days.
+	HalfLifeDays float64 `json:"halfLifeDays,omitempty"`
+}
+
+// GenerateResultSchemaVersion is the version of the format of GenerateResult.
+// It should be incremented when a change makes older results unreadable.
+const GenerateResultSchemaVersion = 1
+
 type GenerateResult struct {
-	Repository  string                 `json:"repository"`
-	Source      string                 `json:"source"`
+	SchemaVersion int                     `json:"schemaVersion"`
+	Repository    string                  `json:"repository"`
+	Source        string                  `json:"source"`
+	GitHubUrl     string                  `json:"gitHubUrl"`
+	Region        string                  `json:"region"`
+	Allocation    string                  `json:"allocation"`
+	GroupBy       string                  `json:"groupBy"`
+	Ownership     string                  `json:"ownership"`
+	Weighting     GenerateResultWeighting `json:"weighting"`
+	// Survival is set only when Survival is requested.
+	Survival *GenerateResultSurvival `json:"survival,omitempty"`
+	Palette  string                  `json:"palette,omitempty"`
+	// Colors are the colours of authors by email. It is empty when Palette is PaletteRank.
+	Colors map[string]string `json:"colors,omitempty"`
+	// Lang is the language the chart is shown in. Results generated before languages are shown in LangEn.
+	Lang        string                 `json:"lang,omitempty"`
 	GeneratedAt time.Time              `json:"generatedAt"`
 	Commits     []GenerateResultCommit `json:"commits"`
 }
 
-func ShowSlowMessage() {
+// sortAuthorsByWeight sorts authors by their score if lines are weighted, otherwise by their lines.
+func sortAuthorsByWeight(authors []GenerateResultCommitLineCountAuthor) {
+	sort.SliceStable(authors, func(i, j int) bool {
+		if authors[i].Score != authors[j].Score {
+			return authors[i].Score > authors[j].Score
+		} else if authors[i].LineCount == authors[j].LineCount {
+			return authors[i].Email < authors[j].Email
+		} else {
+			return authors[i].LineCount > authors[j].LineCount
+		}
+	})
+}
+
+func ShowSlowMessage(ctx context.Context) {
+	logger := LoggerFromContext(ctx)
 	if !IsGitHubAccessTokenProvided() {
-		fmt.Println(fmt.Sprintf(
-			"warning: If the environment variable %v is not set, API searches will be very slow.",
+		logger.Warnf(
+			"If the environment variable %v is not set, API searches will be very slow.",
 			GitHubAccessTokenKey,
-		))
+		)
 	}
 	if !IsUseGitCommandProvided() {
-		fmt.Println(fmt.Sprintf(
-			"warning: If the environment variable %v is not set, blame operation will be very slow.",
+		logger.Warnf(
+			"If the environment variable %v is not set, blame operation will be very slow.",
 			KunitoriUseGitCommandProvidedKey,
-		))
+		)
 	}
 }
 
-func Generate(options *GenerateOptions) (*GenerateResult, error) {
-	var repository *git.Repository
+// Generate counts lines of the sampled commits and allocates areas to their authors.
+// When ctx is done after some commits are counted, the result of those commits is returned together with ctx.Err().
+func Generate(ctx context.Context, options *GenerateOptions) (*GenerateResult, error) {
+	if options.Logger != nil {
+		ctx = WithLogger(ctx, options.Logger)
+	}
+	logger := LoggerFromContext(ctx)
 
-	ShowSlowMessage()
+	ShowSlowMessage(ctx)
 
 	areaInfo, err := GetAreaInfo(options.Region)
 	if err != nil {
 		return nil, err
 	}
 
-	var repositoryLocation string
-	if options.RepositoryUrl != "" {
-		tempDir, err := os.MkdirTemp("", "TestCloneRepository")
+	allocation := options.Allocation
+	if allocation == "" {
+		allocation = AllocationGreedy
+	}
+	allocator := options.Allocator
+	if allocator == nil {
+		allocator, err = GetAllocator(allocation)
 		if err != nil {
 			return nil, err
 		}
-		defer func(path string) {
-			err := os.RemoveAll(path)
-			if err != nil {
-				log.Println(err)
-			}
-		}(tempDir)
+	}
 
-		repositoryLocation = options.RepositoryUrl
+	palette := options.Palette
+	if palette == "" {
+		palette = PaletteRank
+	}
+	if _, ok := Palettes[palette]; !ok {
+		return nil, fmt.Errorf("unknown palette: palette=%v", palette)
+	}
+	if palette == PaletteRank && len(options.Colors) > 0 {
+		return nil, fmt.Errorf("colors cannot be set to the palette: palette=%v", palette)
+	}
+	err = ValidateColors(options.Colors)
+	if err != nil {
+		return nil, err
+	}
 
-		fmt.Println(fmt.Sprintf("open repository: url=%v", repositoryLocation))
+	lang := options.Lang
+	if lang == "" {
+		lang = LangEn
+	}
+	err = ValidateLang(lang)
+	if err != nil {
+		return nil, err
+	}
 
-		repository, err = CloneRepository(repositoryLocation, tempDir)
-		if err != nil {
-			return nil, err
-		}
-	} else if options.RepositoryPath != "" {
-		repositoryLocation = options.RepositoryPath
-		repositoryLocation, err = filepath.Abs(repositoryLocation)
-		if err != nil {
-			return nil, err
+	groupBy := options.GroupBy
+	if groupBy == "" {
+		groupBy = GroupByAuthor
+	}
+	switch groupBy {
+	case GroupByAuthor:
+	case GroupByTeam:
+		if options.Teams == nil {
+			return nil, errors.New("should specify teams to group by team")
 		}
-		fmt.Println(fmt.Sprintf("open repository: path=%v", repositoryLocation))
+	default:
+		return nil, fmt.Errorf("unknown group by: groupBy=%v", groupBy)
+	}
 
-		repository, err = OpenRepository(repositoryLocation)
-		if err != nil {
-			return nil, err
+	ownership := options.Ownership
+	if ownership == "" {
+		ownership = OwnershipBlame
+	}
+	switch ownership {
+	case OwnershipBlame:
+		if options.OwnershipDiff {
+			return nil, errors.New("should use codeowners ownership to diff ownership")
+		}
+	case OwnershipCodeOwners:
+		if options.CountLinesOption.HalfLife > 0 {
+			return nil, errors.New("should use blame ownership to weight lines by recency")
 		}
-	} else {
-		return nil, errors.New("should specify url or path")
+		if options.Survival {
+			return nil, errors.New("should use blame ownership to count survival")
+		}
+	default:
+		return nil, fmt.Errorf("unknown ownership: ownership=%v", ownership)
 	}
 
-	repositoryRemoteLocation, err := GetRemoteLocation(repository)
-	if err != nil {
-		fmt.Println(err)
+	weighting := GenerateResultWeighting{
+		Method: WeightingNone,
 	}
-	if repositoryRemoteLocation == "" {
-		repositoryRemoteLocation = repositoryLocation
+	if options.CountLinesOption.HalfLife > 0 {
+		weighting = GenerateResultWeighting{
+			Method:       WeightingRecency,
+			HalfLifeDays: options.CountLinesOption.HalfLife.Hours() / 24,
+		}
 	}
 
-	fmt.Println(fmt.Sprintf("location: remote=%v", repositoryRemoteLocation))
+	repository, repositoryRemoteLocation, closeRepository, err := openTargetRepository(ctx, options.RepositoryUrl, options.RepositoryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRepository()
 
-	fmt.Println(fmt.Sprintf(
+	logger.Infof(
 		"search commit: since=%v, until=%v, interval=%v, limit=%v",
 		options.SearchCommitsOptions.Since,
 		options.SearchCommitsOptions.Until,
 		options.SearchCommitsOptions.Interval,
 		options.SearchCommitsOptions.Limit,
-	))
+	)
 
-	commits, err := SearchCommits(repository, options.SearchCommitsOptions)
+	progress := newProgressReporter(options.Progress)
+	progress.report(ProgressEvent{
+		Phase: ProgressPhaseSearchCommits,
+	})
+
+	commits, err := SearchCommits(ctx, repository, options.SearchCommitsOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println(fmt.Sprintf("matched commits: count=%v", len(commits)))
+	logger.Infof("matched commits: count=%v", len(commits))
 
-	fmt.Println(fmt.Sprintf(
+	logger.Infof(
 		"count group: filters=%v, authors=%v",
 		len(options.CountLinesOption.Filters),
 		len(options.CountLinesOption.AuthorRegexes),
-	))
+	)
+
+	loginResolver, err := newGitHubLoginResolver(ctx, GetRemoteUrl(repositoryRemoteLocation), options.LoginOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := loginResolver.save(ctx)
+		if err != nil {
+			logger.Warnf("failed to save login cache: err=%v", err)
+		}
+	}()
+
+	login := loginResolver.login
+	if ownership == OwnershipCodeOwners {
+		login = CodeOwnerLogin
+	}
+	isOwner := func(owner string, author string) bool {
+		if strings.EqualFold(owner, author) {
+			return true
+		}
+		if authorLogin := loginResolver.login(author); authorLogin != "" && strings.EqualFold(owner, "@"+authorLogin) {
+			return true
+		}
+		for _, member := range options.Teams[owner] {
+			if strings.EqualFold(member, author) {
+				return true
+			}
+		}
+		return false
+	}
 
-	gitHubLoginNameCache := map[string]*string{}
+	var periods []time.Time
+	if options.Survival {
+		periods = make([]time.Time, 0, len(commits))
+		for i := len(commits) - 1; i >= 0; i-- {
+			periods = append(periods, commits[i].Author.When.UTC())
+		}
+	}
+	survivalSnapshots := make([]survivalSnapshot, 0)
 
 	resultCommits := make([]GenerateResultCommit, 0)
+	newGenerateResult := func() *GenerateResult {
+		var survival *GenerateResultSurvival
+		if options.Survival {
+			survival = newGenerateResultSurvival(periods, survivalSnapshots)
+		}
+		generateResult := &GenerateResult{
+			SchemaVersion: GenerateResultSchemaVersion,
+			Repository:    GetRemoteUrl(repositoryRemoteLocation),
+			Source:        GetSource(repositoryRemoteLocation),
+			GitHubUrl:     GetGitHubBaseUrl(),
+			Region:        areaInfo.Region,
+			Allocation:    allocation,
+			GroupBy:       groupBy,
+			Ownership:     ownership,
+			Weighting:     weighting,
+			Survival:      survival,
+			Lang:          lang,
+			GeneratedAt:   time.Now().UTC(),
+			Commits:       resultCommits,
+		}
+		// the palette and the colors are validated above
+		_ = ColorAuthors(generateResult, palette, options.Colors)
+		return generateResult
+	}
+	partialResult := func(err error) (*GenerateResult, error) {
+		if ctx.Err() != nil && len(resultCommits) > 0 {
+			logger.Debugf("return partial result: commits=%v, err=%v", len(resultCommits), err)
+			return newGenerateResult(), err
+		}
+		return nil, err
+	}
+
 	for index, commit := range commits {
-		fmt.Println(fmt.Sprintf(
-			"count lines: progress=%v/%v, hash=%v, when=%v",
-			index+1,
-			len(commits),
-			commit.Hash.String(),
-			commit.Author.When.UTC().String(),
-		))
-
-		results, err := CountLines(repository, commit, options.CountLinesOption)
-		if err != nil {
-			return nil, err
+		if err := ctx.Err(); err != nil {
+			return partialResult(err)
 		}
 
-		lineCounts := make([]GenerateResultCommitLineCount, 0)
-		for _, result := range results {
-			areaAuthors, err := AllocateAreas(areaInfo, result)
+		commitEvent := ProgressEvent{
+			Phase:       ProgressPhaseCountLines,
+			CommitIndex: index + 1,
+			CommitCount: len(commits),
+			CommitHash:  commit.Hash.String(),
+			CommittedAt: commit.Author.When.UTC(),
+		}
+		progress.report(commitEvent)
+
+		countLinesOption := *options.CountLinesOption
+		countLinesOption.Periods = periods
+		countLinesOption.Progress = func(fileIndex int, fileCount int, file string) {
+			fileEvent := commitEvent
+			fileEvent.FileIndex = fileIndex
+			fileEvent.FileCount = fileCount
+			fileEvent.File = file
+			progress.report(fileEvent)
+		}
+
+		var results, blameResults []*CountLinesResult
+		if ownership == OwnershipCodeOwners {
+			codeOwnersOption := countLinesOption
+			if options.OwnershipDiff {
+				// only the blame below reports files, so that the files of each commit are reported once
+				codeOwnersOption.Progress = nil
+			}
+			results, err = CountLinesByCodeOwners(ctx, commit, &codeOwnersOption)
 			if err != nil {
-				return nil, err
+				return partialResult(err)
 			}
-
-			areas := make([]GenerateResultCommitLineCountArea, 0)
-			authors := make([]GenerateResultCommitLineCountAuthor, 0)
-			for _, areaAuthor := range areaAuthors {
-				areas = append(areas, GenerateResultCommitLineCountArea{
-					Name:        areaAuthor.Area.Name,
-					Size:        areaAuthor.Area.Size,
-					Ratio:       areaAuthor.AreaRatio,
-					AuthorEmail: areaAuthor.Author,
-					AuthorRank:  areaAuthor.AuthorRank,
-				})
-
-				email := areaAuthor.Author
-
-				found := false
-				for _, author := range authors {
-					if author.Email == email {
-						found = true
-						break
-					}
-				}
-				if !found {
-					if gitHubLoginNameCache[email] == nil {
-						login, err := FindLoginByEmail(email)
-						if err != nil {
-							return nil, err
-						}
-						gitHubLoginNameCache[email] = &login
-					}
-
-					gitHubLogin := gitHubLoginNameCache[email]
-
-					authors = append(authors, GenerateResultCommitLineCountAuthor{
-						Email:       email,
-						Name:        result.NameByAuthor[email],
-						GitHubLogin: *gitHubLogin,
-						LineCount:   result.LinesByAuthor[email],
-						Rank:        areaAuthor.AuthorRank,
-					})
+			if options.OwnershipDiff {
+				blameResults, err = CountLines(ctx, repository, commit, &countLinesOption)
+				if err != nil {
+					return partialResult(err)
 				}
 			}
+		} else {
+			results, err = CountLines(ctx, repository, commit, &countLinesOption)
+			if err != nil {
+				return partialResult(err)
+			}
+			blameResults = results
+		}
 
-			notAllocatedAuthors := make([]GenerateResultCommitLineCountAuthor, 0)
-			for email, lineCount := range result.LinesByAuthor {
-				found := false
-				for _, areaAuthor := range areaAuthors {
-					if areaAuthor.Author == email {
-						found = true
-						break
-					}
-				}
+		if blameResults != nil {
+			resolveEvent := commitEvent
+			resolveEvent.Phase = ProgressPhaseResolveLogins
+			progress.report(resolveEvent)
+
+			err = loginResolver.resolve(ctx, blameResults)
+			if err != nil {
+				return partialResult(err)
+			}
+		}
 
-				if !found {
-					if gitHubLoginNameCache[email] == nil {
-						login, err := FindLoginByEmail(email)
-						if err != nil {
-							return nil, err
-						}
-						gitHubLoginNameCache[email] = &login
-					}
-
-					gitHubLogin := gitHubLoginNameCache[email]
-
-					notAllocatedAuthors = append(notAllocatedAuthors, GenerateResultCommitLineCountAuthor{
-						Email:       email,
-						Name:        result.NameByAuthor[email],
-						GitHubLogin: *gitHubLogin,
-						LineCount:   lineCount,
-					})
+		lineCounts := make([]GenerateResultCommitLineCount, 0)
+		groupedResults := make([]*CountLinesResult, 0)
+		for resultIndex, memberResult := range results {
+			result := memberResult
+			if groupBy == GroupByTeam {
+				result, err = GroupLinesByTeam(memberResult, options.Teams)
+				if err != nil {
+					return nil, err
 				}
 			}
 
-			sort.SliceStable(notAllocatedAuthors, func(i, j int) bool {
-				if notAllocatedAuthors[i].LineCount == notAllocatedAuthors[j].LineCount {
-					return notAllocatedAuthors[i].Email < notAllocatedAuthors[j].Email
+			groupedResults = append(groupedResults, result)
+
+			newAuthor := func(email string, lineCount int, rank int) GenerateResultCommitLineCountAuthor {
+				author := GenerateResultCommitLineCountAuthor{
+					Email:     email,
+					Name:      result.NameByAuthor[email],
+					LineCount: lineCount,
+					Score:     result.ScoreByAuthor[email],
+					Rank:      rank,
+				}
+				if members, ok := result.MembersByAuthor[email]; ok {
+					author.Members = newMemberAuthors(memberResult, members, login)
 				} else {
-					return notAllocatedAuthors[i].LineCount > notAllocatedAuthors[j].LineCount
+					author.GitHubLogin = login(email)
 				}
-			})
+				return author
+			}
 
-			for i, notAllocatedAuthor := range notAllocatedAuthors {
-				notAllocatedAuthor.Rank = len(authors) + i + 1
-				notAllocatedAuthors[i] = notAllocatedAuthor
+			areas, authors, err := allocateLineCount(ctx, allocator, areaInfo, result, newAuthor)
+			if err != nil {
+				return nil, err
 			}
 
-			lineCounts = append(lineCounts, GenerateResultCommitLineCount{
+			lineCount := GenerateResultCommitLineCount{
 				FilterRegex: result.Filter.String(),
 				FileCount:   len(result.MatchedFiles),
 				Areas:       areas,
-				Authors:     append(authors, notAllocatedAuthors...),
-			})
+				Authors:     authors,
+			}
+			if options.Details {
+				lineCount.Files, lineCount.Directories = newGenerateResultFiles(result)
+			}
+			if options.OwnershipDiff {
+				lineCount.OwnershipDiffs = DiffOwnership(memberResult, blameResults[resultIndex], isOwner)
+			}
+			lineCounts = append(lineCounts, lineCount)
 		}
 
+		survivalSnapshots = append(survivalSnapshots, survivalSnapshot{
+			committedAt: commit.Author.When.UTC(),
+			results:     groupedResults,
+		})
 		resultCommits = append(resultCommits, GenerateResultCommit{
 			Hash:        commit.Hash.String(),
 			CommittedAt: commit.Author.When.UTC(),
@@ -272,30 +470,125 @@ func Generate(options *GenerateOptions) (*GenerateResult, error) {
 		})
 	}
 
-	return &GenerateResult{
-		Repository:  GetRemoteUrl(repositoryRemoteLocation),
-		Source:      GetSource(repositoryRemoteLocation),
-		GeneratedAt: time.Now().UTC(),
-		Commits:     resultCommits,
-	}, nil
+	progress.report(ProgressEvent{
+		Phase:       ProgressPhaseComplete,
+		CommitIndex: len(commits),
+		CommitCount: len(commits),
+	})
+
+	return newGenerateResult(), nil
+}
+
+// allocateLineCount allocates areas to the authors of result, and ranks the authors without areas after the others.
+// newAuthor builds an author of result with the given lines and rank.
+func allocateLineCount(
+	ctx context.Context,
+	allocator Allocator,
+	areaInfo *AreaInfo,
+	result *CountLinesResult,
+	newAuthor func(email string, lineCount int, rank int) GenerateResultCommitLineCountAuthor,
+) ([]GenerateResultCommitLineCountArea, []GenerateResultCommitLineCountAuthor, error) {
+	areaAuthors, err := allocator.Allocate(ctx, areaInfo, result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	areas := make([]GenerateResultCommitLineCountArea, 0)
+	authors := make([]GenerateResultCommitLineCountAuthor, 0)
+	for _, areaAuthor := range areaAuthors {
+		areas = append(areas, GenerateResultCommitLineCountArea{
+			Name:        areaAuthor.Area.Name,
+			Size:        areaAuthor.Area.Size,
+			Ratio:       areaAuthor.AreaRatio,
+			AuthorEmail: areaAuthor.Author,
+			AuthorRank:  areaAuthor.AuthorRank,
+		})
+
+		email := areaAuthor.Author
+
+		found := false
+		for _, author := range authors {
+			if author.Email == email {
+				found = true
+				break
+			}
+		}
+		if !found {
+			authors = append(authors, newAuthor(email, result.LinesByAuthor[email], areaAuthor.AuthorRank))
+		}
+	}
+
+	notAllocatedAuthors := make([]GenerateResultCommitLineCountAuthor, 0)
+	for email, lineCount := range result.LinesByAuthor {
+		found := false
+		for _, areaAuthor := range areaAuthors {
+			if areaAuthor.Author == email {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			notAllocatedAuthors = append(notAllocatedAuthors, newAuthor(email, lineCount, 0))
+		}
+	}
+
+	sortAuthorsByWeight(notAllocatedAuthors)
+
+	for i, notAllocatedAuthor := range notAllocatedAuthors {
+		notAllocatedAuthor.Rank = len(authors) + i + 1
+		notAllocatedAuthors[i] = notAllocatedAuthor
+	}
+
+	return areas, append(authors, notAllocatedAuthors...), nil
 }
 
-var gitHubRegex = regexp.MustCompile("^https://github\\.com/")
-var gitHubSshRegex = regexp.MustCompile("^git@github\\.com:")
 var gitSuffixRegex = regexp.MustCompile("\\.git$")
 
+type gitHubRemote struct {
+	baseUrl  string
+	urlRegex *regexp.Regexp
+	sshRegex *regexp.Regexp
+}
+
+func gitHubRemotes() []gitHubRemote {
+	baseUrls := []string{GitHubDefaultBaseUrl}
+	if IsGitHubEnterprise() {
+		baseUrls = append(baseUrls, GetGitHubBaseUrl())
+	}
+
+	remotes := make([]gitHubRemote, 0)
+	for _, baseUrl := range baseUrls {
+		parsed, err := url.Parse(baseUrl)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+
+		remotes = append(remotes, gitHubRemote{
+			baseUrl:  baseUrl,
+			urlRegex: regexp.MustCompile("^" + regexp.QuoteMeta(baseUrl) + "/"),
+			sshRegex: regexp.MustCompile("^git@" + regexp.QuoteMeta(parsed.Hostname()) + ":"),
+		})
+	}
+	return remotes
+}
+
 func GetSource(value string) string {
-	if gitHubRegex.MatchString(value) || gitHubSshRegex.MatchString(value) {
-		return "github"
+	for _, remote := range gitHubRemotes() {
+		if remote.urlRegex.MatchString(value) || remote.sshRegex.MatchString(value) {
+			return "github"
+		}
 	}
 	return "unknown"
 }
 
 func GetRemoteUrl(value string) string {
-	if gitHubRegex.MatchString(value) {
-		return gitSuffixRegex.ReplaceAllString(value, "")
-	} else if gitHubSshRegex.MatchString(value) {
-		return gitSuffixRegex.ReplaceAllString("https://github.com/"+gitHubSshRegex.ReplaceAllString(value, ""), "")
+	for _, remote := range gitHubRemotes() {
+		if remote.urlRegex.MatchString(value) {
+			return gitSuffixRegex.ReplaceAllString(value, "")
+		} else if remote.sshRegex.MatchString(value) {
+			return gitSuffixRegex.ReplaceAllString(remote.baseUrl+"/"+remote.sshRegex.ReplaceAllString(value, ""), "")
+		}
 	}
 	return value
 }
@@ -1,9 +1,12 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/dlclark/regexp2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -31,9 +34,18 @@ func TestGenerate(t *testing.T) {
 	}
 
 	expected := GenerateResult{
-		Repository:  "https://github.com/yktakaha4/yokuwakaru-grpc",
-		Source:      "github",
-		GeneratedAt: time.Time{},
+		SchemaVersion: 1,
+		Repository:    "https://github.com/yktakaha4/yokuwakaru-grpc",
+		Source:        "github",
+		GitHubUrl:     "https://github.com",
+		Region:        "__TEST",
+		Allocation:    "greedy",
+		GroupBy:       "author",
+		Ownership:     "blame",
+		Weighting:     GenerateResultWeighting{Method: "none"},
+		Palette:       "rank",
+		Lang:          "en",
+		GeneratedAt:   time.Time{},
 		Commits: []GenerateResultCommit{
 			{
 				Hash:        "2fa8fa83724e394a098890c40cc324fa90b080b5",
@@ -80,7 +92,7 @@ func TestGenerate(t *testing.T) {
 		},
 	}
 
-	result, err := Generate(&options)
+	result, err := Generate(context.Background(), &options)
 	assert.NoError(t, err)
 
 	expected.GeneratedAt = result.GeneratedAt
@@ -99,6 +111,74 @@ func TestGenerate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestGenerate__cancel(t *testing.T) {
+	t.Setenv(KunitoriSkipRequestGitHubApi, "yes")
+
+	options := GenerateOptions{
+		RepositoryPath:       testDataPath("yokuwakaru-grpc"),
+		Region:               "__TEST",
+		SearchCommitsOptions: &SearchCommitsOptions{},
+		CountLinesOption: &CountLinesOption{
+			Filters: []*regexp2.Regexp{
+				regexp2.MustCompile("\\.go$", 0),
+			},
+			AuthorRegexes: []AuthorRegex{},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := Generate(ctx, &options)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+}
+
+func TestGenerateProgress__ownershipDiff(t *testing.T) {
+	t.Setenv(KunitoriSkipRequestGitHubApi, "yes")
+
+	dir := t.TempDir()
+	repository, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+
+	committedAt := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	commitTestFiles(t, repository, map[string]string{
+		"CODEOWNERS": "* @alice\n",
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"pkg/a.go":   "package pkg\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: committedAt})
+
+	fileEvents := make([]ProgressEvent, 0)
+	_, err = Generate(context.Background(), &GenerateOptions{
+		RepositoryPath: dir,
+		Region:         "__TEST",
+		Ownership:      OwnershipCodeOwners,
+		OwnershipDiff:  true,
+		SearchCommitsOptions: &SearchCommitsOptions{
+			Since:    committedAt.Add(-time.Hour),
+			Until:    committedAt.Add(time.Hour),
+			Interval: time.Hour * 24,
+			Limit:    1,
+		},
+		CountLinesOption: &CountLinesOption{
+			Filters:       []*regexp2.Regexp{regexp2.MustCompile("\\.go$", 0)},
+			AuthorRegexes: []AuthorRegex{},
+		},
+		Progress: func(event ProgressEvent) {
+			if event.Phase == ProgressPhaseCountLines && event.FileIndex > 0 {
+				fileEvents = append(fileEvents, event)
+			}
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(fileEvents))
+	for index, event := range fileEvents {
+		assert.Equal(t, index+1, event.FileIndex)
+		assert.Equal(t, 2, event.FileCount)
+	}
+}
+
 func TestGetSource(t *testing.T) {
 	testCases := []struct {
 		value  string
@@ -128,6 +208,15 @@ func TestGetSource(t *testing.T) {
 			assert.Equal(t, testCase.source, source)
 		})
 	}
+
+	t.Run("github enterprise", func(t *testing.T) {
+		t.Setenv(GitHubBaseUrlKey, "https://github.example.co.jp/")
+
+		assert.Equal(t, "github", GetSource("https://github.example.co.jp/yktakaha4/eduterm.git"))
+		assert.Equal(t, "github", GetSource("git@github.example.co.jp:yktakaha4/eduterm.git"))
+		assert.Equal(t, "github", GetSource("https://github.com/yktakaha4/eduterm.git"))
+		assert.Equal(t, "unknown", GetSource("https://gitlab.example.co.jp/yktakaha4/eduterm.git"))
+	})
 }
 
 func TestGetRemoteUrl(t *testing.T) {
@@ -155,4 +244,19 @@ func TestGetRemoteUrl(t *testing.T) {
 			assert.Equal(t, testCase.remoteUrl, remoteUrl)
 		})
 	}
+
+	t.Run("github enterprise", func(t *testing.T) {
+		t.Setenv(GitHubBaseUrlKey, "https://github.example.co.jp")
+
+		assert.Equal(
+			t,
+			"https://github.example.co.jp/yktakaha4/eduterm",
+			GetRemoteUrl("https://github.example.co.jp/yktakaha4/eduterm.git"),
+		)
+		assert.Equal(
+			t,
+			"https://github.example.co.jp/yktakaha4/eduterm",
+			GetRemoteUrl("git@github.example.co.jp:yktakaha4/eduterm.git"),
+		)
+	})
 }
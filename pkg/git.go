@@ -3,37 +3,42 @@ package pkg
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"github.com/dlclark/regexp2"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"log"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 )
 
-func CloneRepository(url string, path string) (*git.Repository, error) {
-	log.Printf("start CloneRepository: url=%+v, path=%+v", url, path)
-	repository, err := git.PlainClone(path, false, &git.CloneOptions{
+func CloneRepository(ctx context.Context, url string, path string) (*git.Repository, error) {
+	logger := LoggerFromContext(ctx)
+
+	logger.Debugf("start CloneRepository: url=%+v, path=%+v", url, path)
+	repository, err := git.PlainCloneContext(ctx, path, false, &git.CloneOptions{
 		URL: url,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("clone completed: repository=%+v", repository)
+	logger.Debugf("clone completed: repository=%+v", repository)
 
 	return repository, nil
 }
 
-func OpenRepository(path string) (*git.Repository, error) {
-	log.Printf("start OpenRepository: path=%+v", path)
+func OpenRepository(ctx context.Context, path string) (*git.Repository, error) {
+	logger := LoggerFromContext(ctx)
+
+	logger.Debugf("start OpenRepository: path=%+v", path)
 
 	repository, err := git.PlainOpen(path)
 	if err != nil {
@@ -43,6 +48,64 @@ func OpenRepository(path string) (*git.Repository, error) {
 	return repository, nil
 }
 
+// openTargetRepository clones url into a temporary directory, or opens path if url is empty.
+// It returns the remote location of the repository, falling back to url or path, and a function removing the clone.
+func openTargetRepository(ctx context.Context, url string, path string) (*git.Repository, string, func(), error) {
+	logger := LoggerFromContext(ctx)
+
+	var repository *git.Repository
+	var repositoryLocation string
+	closeRepository := func() {}
+	if url != "" {
+		tempDir, err := os.MkdirTemp("", "TestCloneRepository")
+		if err != nil {
+			return nil, "", nil, err
+		}
+		closeRepository = func() {
+			err := os.RemoveAll(tempDir)
+			if err != nil {
+				logger.Warnf("failed to remove temporary directory: path=%v, err=%v", tempDir, err)
+			}
+		}
+
+		repositoryLocation = url
+
+		logger.Infof("open repository: url=%v", repositoryLocation)
+
+		repository, err = CloneRepository(ctx, repositoryLocation, tempDir)
+		if err != nil {
+			closeRepository()
+			return nil, "", nil, err
+		}
+	} else if path != "" {
+		var err error
+		repositoryLocation, err = filepath.Abs(path)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		logger.Infof("open repository: path=%v", repositoryLocation)
+
+		repository, err = OpenRepository(ctx, repositoryLocation)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else {
+		return nil, "", nil, errors.New("should specify url or path")
+	}
+
+	repositoryRemoteLocation, err := GetRemoteLocation(repository)
+	if err != nil {
+		logger.Warnf("failed to get remote location: err=%v", err)
+	}
+	if repositoryRemoteLocation == "" {
+		repositoryRemoteLocation = repositoryLocation
+	}
+
+	logger.Infof("location: remote=%v", repositoryRemoteLocation)
+
+	return repository, repositoryRemoteLocation, closeRepository, nil
+}
+
 func GetRemoteLocation(repository *git.Repository) (string, error) {
 	remote, err := repository.Remote("origin")
 	if err != nil {
@@ -65,8 +128,10 @@ type SearchCommitsOptions struct {
 
 const SearchCommitMaxLimit = 15
 
-func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([]*object.Commit, error) {
-	log.Printf("start SearchCommits: repository=%+v, options=%+v", repository, options)
+func SearchCommits(ctx context.Context, repository *git.Repository, options *SearchCommitsOptions) ([]*object.Commit, error) {
+	logger := LoggerFromContext(ctx)
+
+	logger.Debugf("start SearchCommits: repository=%+v, options=%+v", repository, options)
 
 	reference, err := repository.Head()
 	if err != nil {
@@ -87,7 +152,7 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 	}
 	until = until.UTC()
 
-	log.Printf("filter commits: hash=%v, since=%+v, until=%+v", hash, since, until)
+	logger.Debugf("filter commits: hash=%v, since=%+v, until=%+v", hash, since, until)
 
 	commitIter, err := repository.Log(&git.LogOptions{
 		From: hash,
@@ -99,6 +164,10 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 	filteredCommits := make([]*object.Commit, 0)
 	commitCount, pickCount, skipCount := 0, 0, 0
 	err = commitIter.ForEach(func(commit *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		commitCount++
 
 		commitWhen := commit.Author.When.UTC()
@@ -111,8 +180,11 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf(
+	logger.Debugf(
 		"filter complete: commitCount=%+v, pickCount=%+v, skipCount=%+v",
 		commitCount, pickCount, skipCount,
 	)
@@ -121,13 +193,13 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 		return filteredCommits, nil
 	}
 
-	log.Printf("sort commits: count=%+v", len(filteredCommits))
+	logger.Debugf("sort commits: count=%+v", len(filteredCommits))
 
 	sort.SliceStable(filteredCommits, func(i, j int) bool {
 		return filteredCommits[i].Author.When.UTC().After(filteredCommits[j].Author.When.UTC())
 	})
 
-	log.Printf(
+	logger.Debugf(
 		"sort complete: latest=%+v, least=%+v",
 		filteredCommits[0].Author.When.UTC(),
 		filteredCommits[len(filteredCommits)-1].Author.When.UTC(),
@@ -143,7 +215,7 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 		limit = options.Limit
 	}
 
-	log.Printf("thin commits: interval=%+v, limit=%+v", interval, limit)
+	logger.Debugf("thin commits: interval=%+v, limit=%+v", interval, limit)
 
 	commits := make([]*object.Commit, 0)
 	pickCount, skipCount = 0, 0
@@ -160,7 +232,7 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 			}
 		}
 
-		log.Printf("hash=%+v, commitWhen=%+v", hash, commitWhen)
+		logger.Debugf("hash=%+v, commitWhen=%+v", hash, commitWhen)
 		commits = append(commits, commit)
 		pickCount++
 
@@ -169,7 +241,7 @@ func SearchCommits(repository *git.Repository, options *SearchCommitsOptions) ([
 		}
 	}
 
-	log.Printf(
+	logger.Debugf(
 		"thin completed: commitCount=%+v, pickCount=%+v, skipCount=%+v",
 		len(commits), pickCount, skipCount,
 	)
@@ -185,25 +257,53 @@ type AuthorRegex struct {
 type CountLinesOption struct {
 	Filters       []*regexp2.Regexp
 	AuthorRegexes []AuthorRegex
+	// Progress is called after each file matched by any filter is counted.
+	Progress func(fileIndex int, fileCount int, file string)
+	// HalfLife weights each line by its age relative to the counted commit, halving every HalfLife.
+	// Lines are not weighted if it is zero.
+	HalfLife time.Duration
+	// Periods are the ends of periods in chronological order. Lines are also counted by the period they are added in if it is set.
+	Periods []time.Time
 }
 
 type CountLinesResult struct {
 	Filter        *regexp2.Regexp
 	LinesByAuthor map[string]int
 	NameByAuthor  map[string]string
+	HashByAuthor  map[string]string
 	MatchedFiles  []string
+	// ScoreByAuthor is the sum of the recency weights of lines by author. It is nil if lines are not weighted.
+	ScoreByAuthor map[string]float64
+	// LinesByPeriod is the number of lines by author and the index of Periods they are added in.
+	// It is nil if Periods is not set.
+	LinesByPeriod map[string][]int
+	// LinesByFile is the number of lines of each matched file by author.
+	LinesByFile map[string]map[string]int
+	// MembersByAuthor lists the authors aggregated into each group, when the result is grouped by team.
+	MembersByAuthor map[string][]string
 }
 
-func CountLines(repository *git.Repository, commit *object.Commit, options *CountLinesOption) ([]*CountLinesResult, error) {
-	log.Printf("start CountLines: commit=%+v, options=%+v", commit.Hash, options)
+func CountLines(ctx context.Context, repository *git.Repository, commit *object.Commit, options *CountLinesOption) ([]*CountLinesResult, error) {
+	logger := LoggerFromContext(ctx)
+
+	logger.Debugf("start CountLines: commit=%+v, options=%+v", commit.Hash, options)
 	results := make([]*CountLinesResult, 0)
 	for _, filter := range options.Filters {
-		results = append(results, &CountLinesResult{
+		result := &CountLinesResult{
 			Filter:        filter,
 			LinesByAuthor: map[string]int{},
 			NameByAuthor:  map[string]string{},
+			HashByAuthor:  map[string]string{},
 			MatchedFiles:  make([]string, 0),
-		})
+			LinesByFile:   map[string]map[string]int{},
+		}
+		if options.HalfLife > 0 {
+			result.ScoreByAuthor = map[string]float64{}
+		}
+		if len(options.Periods) > 0 {
+			result.LinesByPeriod = map[string][]int{}
+		}
+		results = append(results, result)
 	}
 
 	tree, err := commit.Tree()
@@ -211,14 +311,14 @@ func CountLines(repository *git.Repository, commit *object.Commit, options *Coun
 		return nil, err
 	}
 
-	fileCount, targetCount, linesCount, errorCount := 0, 0, 0, 0
-	err = tree.Files().ForEach(func(file *object.File) error {
-		fileCount++
-
-		if file.Type() != plumbing.BlobObject {
-			return nil
-		}
+	files, err := matchFiles(ctx, tree, options.Filters)
+	if err != nil {
+		return nil, err
+	}
 
+	commitWhen := commit.Author.When.UTC()
+	targetCount, linesCount, errorCount := 0, 0, 0
+	blameFile := func(file *object.File) error {
 		for _, result := range results {
 			isMatch, err := result.Filter.MatchString(file.Name)
 			if err != nil {
@@ -229,20 +329,21 @@ func CountLines(repository *git.Repository, commit *object.Commit, options *Coun
 			}
 
 			result.MatchedFiles = append(result.MatchedFiles, file.Name)
+			result.LinesByFile[file.Name] = map[string]int{}
 			targetCount++
 
-			log.Printf("match: file=%+v, filter=%+v", file.Name, result.Filter.String())
+			logger.Debugf("match: file=%+v, filter=%+v", file.Name, result.Filter.String())
 
 			lines := make([]*git.Line, 0)
 			if IsUseGitCommandProvided() {
-				lines, err = BlameWithGitCommand(repository, commit, file.Name)
+				lines, err = BlameWithGitCommand(ctx, repository, commit, file.Name)
 				if err != nil {
 					return err
 				}
 			} else {
 				blameResult, err := git.Blame(commit, file.Name)
 				if err != nil {
-					log.Printf("failed to blame: err=%v", err)
+					logger.Debugf("failed to blame: err=%v", err)
 					errorCount++
 					continue
 				}
@@ -262,11 +363,25 @@ func CountLines(repository *git.Repository, commit *object.Commit, options *Coun
 					}
 				}
 				result.LinesByAuthor[author] += 1
+				result.LinesByFile[file.Name][author] += 1
+				if result.ScoreByAuthor != nil {
+					result.ScoreByAuthor[author] += RecencyWeight(commitWhen.Sub(line.Date), options.HalfLife)
+				}
+				if result.LinesByPeriod != nil {
+					if result.LinesByPeriod[author] == nil {
+						result.LinesByPeriod[author] = make([]int, len(options.Periods))
+					}
+					result.LinesByPeriod[author][PeriodIndex(options.Periods, line.Date)] += 1
+				}
+
+				if result.HashByAuthor[author] == "" {
+					result.HashByAuthor[author] = line.Hash.String()
+				}
 
 				if result.NameByAuthor[author] == "" {
 					lineCommit, err := repository.CommitObject(line.Hash)
 					if err != nil {
-						log.Printf("failed to get line commit: err=%v", err)
+						logger.Debugf("failed to get line commit: err=%v", err)
 						continue
 					}
 					result.NameByAuthor[author] = lineCommit.Author.Name
@@ -276,18 +391,111 @@ func CountLines(repository *git.Repository, commit *object.Commit, options *Coun
 			}
 		}
 
+		return nil
+	}
+
+	for fileIndex, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err := blameFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if options.Progress != nil {
+			options.Progress(fileIndex+1, len(files), file.Name)
+		}
+	}
+
+	logger.Debugf(
+		"traverse complete: matchedFiles=%+v, targetCount=%v, linesCount=%+v, errorCount=%v",
+		len(files), targetCount, linesCount, errorCount,
+	)
+
+	return results, nil
+}
+
+// MinRecencyWeight is the weight of lines older than about 40 half-lives.
+// It keeps weights from underflowing to 0, so that old lines are still compared by their numbers.
+const MinRecencyWeight = 1e-12
+
+// RecencyWeight returns the weight of a line of age, which is 1 for a new line and halves every halfLife down to MinRecencyWeight.
+func RecencyWeight(age time.Duration, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Max(math.Pow(0.5, float64(age)/float64(halfLife)), MinRecencyWeight)
+}
+
+// PeriodIndex returns the index of the period when is in. Periods end at periods, and the last period also holds when after it.
+func PeriodIndex(periods []time.Time, when time.Time) int {
+	index := sort.Search(len(periods), func(i int) bool {
+		return !periods[i].Before(when)
+	})
+	if index >= len(periods) {
+		index = len(periods) - 1
+	}
+	return index
+}
+
+// Weights returns ScoreByAuthor if lines are weighted, otherwise LinesByAuthor.
+// LinesByAuthor is also returned if no score is positive, e.g. results loaded from older versions.
+func (r *CountLinesResult) Weights() map[string]float64 {
+	totalScore := float64(0)
+	for _, score := range r.ScoreByAuthor {
+		totalScore += score
+	}
+
+	weights := map[string]float64{}
+	if totalScore > 0 {
+		for author, score := range r.ScoreByAuthor {
+			weights[author] = score
+		}
+	} else {
+		for author, lines := range r.LinesByAuthor {
+			weights[author] = float64(lines)
+		}
+	}
+	return weights
+}
+
+// matchFiles walks tree once and returns the files matched by any of filters, in the order of the tree.
+// Callers count the files to show progress from the same walk.
+func matchFiles(ctx context.Context, tree *object.Tree, filters []*regexp2.Regexp) ([]*object.File, error) {
+	files := make([]*object.File, 0)
+	walkedCount := 0
+	err := tree.Files().ForEach(func(file *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		walkedCount++
+
+		if file.Type() != plumbing.BlobObject {
+			return nil
+		}
+
+		for _, filter := range filters {
+			isMatch, err := filter.MatchString(file.Name)
+			if err != nil {
+				return err
+			}
+			if isMatch {
+				files = append(files, file)
+				break
+			}
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf(
-		"traverse complete: fileCount=%+v, targetCount=%v, linesCount=%+v",
-		fileCount, targetCount, linesCount,
-	)
+	LoggerFromContext(ctx).Debugf("match files: walkedCount=%v, matchedCount=%v", walkedCount, len(files))
 
-	return results, nil
+	return files, nil
 }
 
 const KunitoriUseGitCommandProvidedKey = "KUNITORI_USE_GIT_COMMAND"
@@ -299,7 +507,9 @@ func IsUseGitCommandProvided() bool {
 var blameLineRegexp = regexp.MustCompile("^\\w+\\s\\d+\\)\\s")
 var invalidCharacterRegexp = regexp.MustCompile("\\W")
 
-func BlameWithGitCommand(repository *git.Repository, commit *object.Commit, file string) ([]*git.Line, error) {
+func BlameWithGitCommand(ctx context.Context, repository *git.Repository, commit *object.Commit, file string) ([]*git.Line, error) {
+	logger := LoggerFromContext(ctx)
+
 	workTree, err := repository.Worktree()
 	if err != nil {
 		return nil, err
@@ -307,7 +517,7 @@ func BlameWithGitCommand(repository *git.Repository, commit *object.Commit, file
 
 	repoRoot := workTree.Filesystem.Root()
 	hash := commit.Hash.String()
-	blameResult, err := exec.Command("git", "-C", repoRoot, "blame", "-sl", hash, file).Output()
+	blameResult, err := exec.CommandContext(ctx, "git", "-C", repoRoot, "blame", "-sl", hash, file).Output()
 	if err != nil {
 		return nil, err
 	}
@@ -327,7 +537,7 @@ func BlameWithGitCommand(repository *git.Repository, commit *object.Commit, file
 			lineHash := plumbing.NewHash(hashStr)
 			lineCommit, err := repository.CommitObject(lineHash)
 			if err != nil {
-				log.Printf(fmt.Sprintf("invalid commit: hash=%v, error=%v", hashStr, err))
+				logger.Debugf("invalid commit: hash=%v, error=%v", hashStr, err)
 				badCommit[hashStr] = true
 				continue
 			}
@@ -1,11 +1,13 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"github.com/dlclark/regexp2"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -35,7 +37,7 @@ func TestCloneRepository(t *testing.T) {
 				assert.NoError(t, err)
 			}(tempDir)
 
-			repository, err := CloneRepository(testCase.url, tempDir)
+			repository, err := CloneRepository(context.Background(), testCase.url, tempDir)
 			assert.NoError(t, err)
 			assert.NotNil(t, repository)
 		})
@@ -55,7 +57,7 @@ func TestOpenRepository(t *testing.T) {
 
 	for index, testCase := range testCases {
 		t.Run(fmt.Sprintf("case_%v", index), func(t *testing.T) {
-			repository, err := OpenRepository(testCase.path)
+			repository, err := OpenRepository(context.Background(), testCase.path)
 			assert.NoError(t, err)
 			assert.NotNil(t, repository)
 
@@ -125,7 +127,7 @@ func TestSearchCommits(t *testing.T) {
 
 	for index, testCase := range testCases {
 		t.Run(fmt.Sprintf("case_%v", index), func(t *testing.T) {
-			commits, err := SearchCommits(testCase.repository, testCase.options)
+			commits, err := SearchCommits(context.Background(), testCase.repository, testCase.options)
 			assert.NoError(t, err)
 			assert.NotNil(t, commits)
 
@@ -314,7 +316,7 @@ func TestCountLines(t *testing.T) {
 		}
 
 		t.Run(fmt.Sprintf("case_%v", index), func(t *testing.T) {
-			results, err := CountLines(testCase.repository, testCase.commit, testCase.options)
+			results, err := CountLines(context.Background(), testCase.repository, testCase.commit, testCase.options)
 			assert.NoError(t, err)
 			assert.Equal(t, len(testCase.results), len(results))
 
@@ -355,16 +357,35 @@ func TestCountLines__diff(t *testing.T) {
 		AuthorRegexes: []AuthorRegex{},
 	}
 
-	goGitResult, err := CountLines(djangoRepository, djangoHeadCommit, &option)
+	goGitResult, err := CountLines(context.Background(), djangoRepository, djangoHeadCommit, &option)
 	assert.NoError(t, err)
 
 	t.Setenv(KunitoriUseGitCommandProvidedKey, "1")
-	gitCommandResult, err := CountLines(djangoRepository, djangoHeadCommit, &option)
+	gitCommandResult, err := CountLines(context.Background(), djangoRepository, djangoHeadCommit, &option)
 	assert.NoError(t, err)
 
 	assert.NotEqual(t, goGitResult, gitCommandResult)
 }
 
+func TestCountLines__cancel(t *testing.T) {
+	djangoRepository := openTestRepository("django")
+	djangoHeadCommit := getHeadCommit(djangoRepository)
+
+	option := CountLinesOption{
+		Filters: []*regexp2.Regexp{
+			regexp2.MustCompile(".+", 0),
+		},
+		AuthorRegexes: []AuthorRegex{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := CountLines(ctx, djangoRepository, djangoHeadCommit, &option)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, results)
+}
+
 func TestBlameWithGitCommand(t *testing.T) {
 	t.Setenv(KunitoriUseGitCommandProvidedKey, "1")
 
@@ -380,7 +401,7 @@ func TestBlameWithGitCommand(t *testing.T) {
 
 	maskRegex := regexp.MustCompile("@.+$")
 
-	results, err := CountLines(djangoRepository, djangoHeadCommit, &option)
+	results, err := CountLines(context.Background(), djangoRepository, djangoHeadCommit, &option)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(results))
 
@@ -497,3 +518,184 @@ func getHeadCommit(repository *git.Repository) *object.Commit {
 
 	return commit
 }
+
+func TestRecencyWeight(t *testing.T) {
+	halfLife := time.Hour * 24 * 365
+
+	testCases := []struct {
+		name     string
+		age      time.Duration
+		halfLife time.Duration
+		expected float64
+	}{
+		{name: "new line", age: 0, halfLife: halfLife, expected: 1},
+		{name: "line after commit", age: -time.Hour, halfLife: halfLife, expected: 1},
+		{name: "one half-life", age: halfLife, halfLife: halfLife, expected: 0.5},
+		{name: "two half-lives", age: halfLife * 2, halfLife: halfLife, expected: 0.25},
+		{name: "no weighting", age: halfLife * 2, halfLife: 0, expected: 1},
+		{name: "underflow", age: time.Hour * 24 * 45, halfLife: time.Hour, expected: MinRecencyWeight},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.InDelta(t, testCase.expected, RecencyWeight(testCase.age, testCase.halfLife), 1e-9)
+			assert.Greater(t, RecencyWeight(testCase.age, testCase.halfLife), float64(0))
+		})
+	}
+}
+
+func TestCountLinesResult_Weights(t *testing.T) {
+	result := &CountLinesResult{
+		LinesByAuthor: map[string]int{"alice@example.com": 10, "bob@example.com": 4},
+	}
+	assert.Equal(t, map[string]float64{"alice@example.com": 10, "bob@example.com": 4}, result.Weights())
+
+	result.ScoreByAuthor = map[string]float64{"alice@example.com": 2.5, "bob@example.com": 4}
+	assert.Equal(t, map[string]float64{"alice@example.com": 2.5, "bob@example.com": 4}, result.Weights())
+
+	result.ScoreByAuthor = map[string]float64{"alice@example.com": 0, "bob@example.com": 0}
+	assert.Equal(t, map[string]float64{"alice@example.com": 10, "bob@example.com": 4}, result.Weights())
+}
+
+func TestCountLines__halfLifeUnderflow(t *testing.T) {
+	repository, err := git.PlainInit(t.TempDir(), false)
+	assert.NoError(t, err)
+
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitTestFiles(t, repository, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: since})
+	commit := commitTestFiles(t, repository, map[string]string{
+		"README.md": "# readme\n",
+	}, &object.Signature{Name: "Bob", Email: "bob@example.com", When: since.Add(time.Hour * 24 * 365)})
+
+	results, err := CountLines(context.Background(), repository, commit, &CountLinesOption{
+		Filters:  []*regexp2.Regexp{regexp2.MustCompile("\\.go$", 0)},
+		HalfLife: time.Hour,
+	})
+	assert.NoError(t, err)
+	assert.Greater(t, results[0].ScoreByAuthor["alice@example.com"], float64(0))
+
+	areaInfo, err := GetAreaInfo("__TEST")
+	assert.NoError(t, err)
+	for _, allocation := range []string{AllocationGreedy, AllocationLargestRemainder, AllocationDHondt, AllocationContiguous} {
+		allocator, err := GetAllocator(allocation)
+		assert.NoError(t, err)
+
+		areaAuthors, err := allocator.Allocate(context.Background(), areaInfo, results[0])
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(areaAuthors), allocation)
+		for _, areaAuthor := range areaAuthors {
+			assert.Equal(t, "alice@example.com", areaAuthor.Author, allocation)
+			assert.False(t, math.IsNaN(areaAuthor.AreaRatio), allocation)
+		}
+	}
+}
+
+func TestCountLines__halfLife(t *testing.T) {
+	repository, err := git.PlainInit(t.TempDir(), false)
+	assert.NoError(t, err)
+
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitTestFiles(t, repository, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: since})
+	commit := commitTestFiles(t, repository, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n\nfunc sub() {}\n",
+	}, &object.Signature{Name: "Bob", Email: "bob@example.com", When: since.Add(time.Hour * 24 * 10)})
+
+	results, err := CountLines(context.Background(), repository, commit, &CountLinesOption{
+		Filters:  []*regexp2.Regexp{regexp2.MustCompile("\\.go$", 0)},
+		HalfLife: time.Hour * 24 * 10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, map[string]int{"alice@example.com": 3, "bob@example.com": 2}, results[0].LinesByAuthor)
+	assert.InDelta(t, 1.5, results[0].ScoreByAuthor["alice@example.com"], 1e-9)
+	assert.InDelta(t, 2, results[0].ScoreByAuthor["bob@example.com"], 1e-9)
+}
+
+func TestCountLines__progress(t *testing.T) {
+	repository, err := git.PlainInit(t.TempDir(), false)
+	assert.NoError(t, err)
+
+	commit := commitTestFiles(t, repository, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"pkg/a.go":  "package pkg\n",
+		"README.md": "# readme\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()})
+
+	progress := make([][2]int, 0)
+	progressFiles := make([]string, 0)
+	results, err := CountLines(context.Background(), repository, commit, &CountLinesOption{
+		Filters: []*regexp2.Regexp{
+			regexp2.MustCompile("\\.go$", 0),
+			regexp2.MustCompile("^main\\.go$", 0),
+		},
+		Progress: func(fileIndex int, fileCount int, file string) {
+			progress = append(progress, [2]int{fileIndex, fileCount})
+			progressFiles = append(progressFiles, file)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int{{1, 2}, {2, 2}}, progress)
+	assert.Equal(t, []string{"main.go", "pkg/a.go"}, progressFiles)
+	assert.Equal(t, []string{"main.go", "pkg/a.go"}, results[0].MatchedFiles)
+	assert.Equal(t, []string{"main.go"}, results[1].MatchedFiles)
+}
+
+// commitTestFiles writes files into the work tree of repository and commits them.
+func commitTestFiles(t *testing.T, repository *git.Repository, files map[string]string, author *object.Signature) *object.Commit {
+	workTree, err := repository.Worktree()
+	assert.NoError(t, err)
+
+	root := workTree.Filesystem.Root()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	assert.NoError(t, workTree.AddGlob("."))
+	hash, err := workTree.Commit("commit", &git.CommitOptions{
+		Author: author,
+	})
+	assert.NoError(t, err)
+
+	commit, err := repository.CommitObject(hash)
+	assert.NoError(t, err)
+	return commit
+}
+
+func TestPeriodIndex(t *testing.T) {
+	periods := []time.Time{
+		time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, 0, PeriodIndex(periods, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, PeriodIndex(periods, periods[0]))
+	assert.Equal(t, 1, PeriodIndex(periods, time.Date(2022, 1, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1, PeriodIndex(periods, periods[1]))
+	assert.Equal(t, 1, PeriodIndex(periods, time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestCountLines__periods(t *testing.T) {
+	repository, err := git.PlainInit(t.TempDir(), false)
+	assert.NoError(t, err)
+
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := commitTestFiles(t, repository, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: since})
+	second := commitTestFiles(t, repository, map[string]string{
+		"main.go": "package main\n\nfunc sub() {}\n",
+	}, &object.Signature{Name: "Alice", Email: "alice@example.com", When: since.Add(time.Hour * 24)})
+
+	results, err := CountLines(context.Background(), repository, second, &CountLinesOption{
+		Filters: []*regexp2.Regexp{regexp2.MustCompile("\\.go$", 0)},
+		Periods: []time.Time{first.Author.When, second.Author.When},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]int{"alice@example.com": {2, 1}}, results[0].LinesByPeriod)
+}
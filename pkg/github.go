@@ -1,62 +1,66 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/go-github/v48/github"
 	"golang.org/x/oauth2"
-	"log"
+	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
-	"time"
 )
 
 const GitHubAccessTokenKey = "KUNITORI_GITHUB_ACCESS_TOKEN"
+const GitHubBaseUrlKey = "KUNITORI_GITHUB_BASE_URL"
 const KunitoriSkipRequestGitHubApi = "KUNITORI_SKIP_REQUEST_GITHUB_API"
 
-func FindLoginByEmail(email string) (string, error) {
+const GitHubDefaultBaseUrl = "https://github.com"
+
+// FindLoginsByCommitsBatchSize is the number of commits resolved by one GraphQL query.
+const FindLoginsByCommitsBatchSize = 50
+
+var gitHubNoReplyEmailRegex = regexp.MustCompile("@users\\.noreply\\.github\\.com$")
+
+func FindLoginByEmail(ctx context.Context, email string) (string, error) {
+	logger := LoggerFromContext(ctx)
+
 	if os.Getenv(KunitoriSkipRequestGitHubApi) == "yes" {
 		return "kunitori", nil
 	}
 
-	// https://docs.github.com/ja/rest/search?apiVersion=2022-11-28#search-users
-	sleep := 7
-	if IsGitHubAccessTokenProvided() {
-		sleep = 3
-	}
-
 	if email == "" {
 		return "", nil
 	}
 
-	log.Printf("start FindLoginByEmail: email=%v, sleep=%v", email, sleep)
-
-	gitHubNoReplyEmailRegex := regexp.MustCompile("@users\\.noreply\\.github\\.com$")
+	logger.Debugf("start FindLoginByEmail: email=%v", email)
 
 	if gitHubNoReplyEmailRegex.MatchString(email) {
 		emailHost := gitHubNoReplyEmailRegex.ReplaceAllString(email, "")
 		parts := strings.Split(emailHost, "+")
 		if len(parts) == 2 {
 			login := parts[1]
-			log.Printf("github user email: email=%v, login=%v", email, login)
+			logger.Debugf("github user email: email=%v, login=%v", email, login)
 
 			return login, nil
 		} else {
-			log.Printf("invalid github email: email=%v", email)
+			logger.Debugf("invalid github email: email=%v", email)
 		}
 	}
 
 	query := fmt.Sprintf("%v in:email", email)
 
-	log.Printf("search users: query=%v", query)
+	logger.Debugf("search users: query=%v", query)
 
-	client, ctx := createGitHubClient()
+	client, err := createGitHubClient(ctx)
+	if err != nil {
+		return "", err
+	}
 
 	result, _, err := client.Search.Users(ctx, query, nil)
-
-	time.Sleep(time.Duration(sleep) * time.Second)
-
 	if err != nil {
 		return "", err
 	}
@@ -64,30 +68,257 @@ func FindLoginByEmail(email string) (string, error) {
 	if result.GetTotal() > 0 {
 		login := *result.Users[0].Login
 
-		log.Printf("user found: email=%v, login=%v", email, login)
+		logger.Debugf("user found: email=%v, login=%v", email, login)
 		return login, nil
 	} else {
-		log.Printf("user not found: email=%v", email)
+		logger.Debugf("user not found: email=%v", email)
 		return "", nil
 	}
 }
 
+// FindLoginsByCommits resolves GitHub logins from the authors of commits in the repository.
+// hashByEmail maps an author email to one of the commit hashes written by the author.
+// Emails whose commit is not linked to a GitHub account are not contained in the returned map.
+func FindLoginsByCommits(ctx context.Context, repositoryUrl string, hashByEmail map[string]string) (map[string]string, error) {
+	logger := LoggerFromContext(ctx)
+
+	logins := map[string]string{}
+	if os.Getenv(KunitoriSkipRequestGitHubApi) == "yes" {
+		return logins, nil
+	}
+
+	owner, name, ok := parseGitHubRepository(repositoryUrl)
+	if !ok {
+		logger.Debugf("not a github repository: repositoryUrl=%v", repositoryUrl)
+		return logins, nil
+	}
+
+	emails := make([]string, 0)
+	for email, hash := range hashByEmail {
+		if email != "" && hash != "" {
+			emails = append(emails, email)
+		}
+	}
+	sort.Strings(emails)
+
+	logger.Debugf("start FindLoginsByCommits: owner=%v, name=%v, emails=%v", owner, name, len(emails))
+
+	if IsGitHubAccessTokenProvided() {
+		for start := 0; start < len(emails); start += FindLoginsByCommitsBatchSize {
+			end := start + FindLoginsByCommitsBatchSize
+			if end > len(emails) {
+				end = len(emails)
+			}
+
+			batch := map[string]string{}
+			for _, email := range emails[start:end] {
+				batch[email] = hashByEmail[email]
+			}
+
+			batchLogins, err := findLoginsByCommitsWithGraphQL(ctx, owner, name, batch)
+			if err != nil {
+				return nil, err
+			}
+			for email, login := range batchLogins {
+				logins[email] = login
+			}
+		}
+	} else {
+		client, err := createGitHubClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, email := range emails {
+			commit, response, err := client.Repositories.GetCommit(ctx, owner, name, hashByEmail[email], nil)
+			if err != nil {
+				if response != nil && (response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusUnprocessableEntity) {
+					logger.Debugf("commit not found: email=%v, hash=%v", email, hashByEmail[email])
+					continue
+				}
+				return nil, err
+			}
+
+			if login := commit.GetAuthor().GetLogin(); login != "" {
+				logins[email] = login
+			}
+		}
+	}
+
+	logger.Debugf("complete FindLoginsByCommits: found=%v, notFound=%v", len(logins), len(emails)-len(logins))
+
+	return logins, nil
+}
+
+func findLoginsByCommitsWithGraphQL(ctx context.Context, owner string, name string, hashByEmail map[string]string) (map[string]string, error) {
+	logger := LoggerFromContext(ctx)
+
+	emails := make([]string, 0)
+	for email := range hashByEmail {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	objects := make([]string, 0)
+	for index, email := range emails {
+		hash := hashByEmail[email]
+		if !gitHashRegex.MatchString(hash) {
+			return nil, fmt.Errorf("invalid commit hash: email=%v, hash=%v", email, hash)
+		}
+		objects = append(objects, fmt.Sprintf(
+			"c%v: object(oid: %q) { ... on Commit { author { user { login } } } }",
+			index,
+			hash,
+		))
+	}
+
+	query := fmt.Sprintf(
+		"query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) { %v } }",
+		strings.Join(objects, " "),
+	)
+
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"variables": map[string]string{
+			"owner": owner,
+			"name":  name,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := createGitHubHttpClient(ctx)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, getGitHubGraphQLUrl(), bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graphql request failed: status=%v", response.Status)
+	}
+
+	var responseBody struct {
+		Data struct {
+			Repository map[string]*struct {
+				Author struct {
+					User *struct {
+						Login string `json:"login"`
+					} `json:"user"`
+				} `json:"author"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if responseBody.Data.Repository == nil && len(responseBody.Errors) > 0 {
+		return nil, fmt.Errorf("graphql request failed: message=%v", responseBody.Errors[0].Message)
+	}
+	for _, responseError := range responseBody.Errors {
+		logger.Debugf("graphql error: message=%v", responseError.Message)
+	}
+
+	logins := map[string]string{}
+	for index, email := range emails {
+		object := responseBody.Data.Repository[fmt.Sprintf("c%v", index)]
+		if object != nil && object.Author.User != nil && object.Author.User.Login != "" {
+			logins[email] = object.Author.User.Login
+		}
+	}
+
+	return logins, nil
+}
+
+func IsGitHubNoReplyEmail(email string) bool {
+	return gitHubNoReplyEmailRegex.MatchString(email)
+}
+
+var gitHashRegex = regexp.MustCompile("^[0-9a-f]{40}$")
+
+func parseGitHubRepository(repositoryUrl string) (string, string, bool) {
+	remoteUrl := GetRemoteUrl(repositoryUrl)
+	for _, remote := range gitHubRemotes() {
+		if !remote.urlRegex.MatchString(remoteUrl) {
+			continue
+		}
+
+		parts := strings.Split(strings.Trim(remote.urlRegex.ReplaceAllString(remoteUrl, ""), "/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+func getGitHubGraphQLUrl() string {
+	if IsGitHubEnterprise() {
+		return GetGitHubBaseUrl() + "/api/graphql"
+	}
+	return "https://api.github.com/graphql"
+}
+
 func IsGitHubAccessTokenProvided() bool {
 	return len(os.Getenv(GitHubAccessTokenKey)) > 0
 }
 
-func createGitHubClient() (*github.Client, context.Context) {
-	ctx := context.Background()
+// GetGitHubBaseUrl returns the web URL of the GitHub instance used for login lookups and links.
+// It is https://github.com unless KUNITORI_GITHUB_BASE_URL points to a GitHub Enterprise Server.
+func GetGitHubBaseUrl() string {
+	baseUrl := strings.TrimSuffix(os.Getenv(GitHubBaseUrlKey), "/")
+	if baseUrl == "" {
+		return GitHubDefaultBaseUrl
+	}
+	return baseUrl
+}
+
+func IsGitHubEnterprise() bool {
+	return GetGitHubBaseUrl() != GitHubDefaultBaseUrl
+}
+
+func createGitHubHttpClient(ctx context.Context) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
 	if IsGitHubAccessTokenProvided() {
 		token := os.Getenv(GitHubAccessTokenKey)
 
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
-		tc := oauth2.NewClient(ctx, ts)
+		transport = oauth2.NewClient(ctx, ts).Transport
+	}
 
-		return github.NewClient(tc), ctx
-	} else {
-		return github.NewClient(nil), ctx
+	return &http.Client{
+		Transport: newRateLimitTransport(transport),
 	}
 }
+
+func createGitHubClient(ctx context.Context) (*github.Client, error) {
+	httpClient := createGitHubHttpClient(ctx)
+
+	if IsGitHubEnterprise() {
+		baseUrl := GetGitHubBaseUrl()
+		LoggerFromContext(ctx).Debugf("use github enterprise: baseUrl=%v", baseUrl)
+
+		client, err := github.NewEnterpriseClient(baseUrl, baseUrl, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
+	return github.NewClient(httpClient), nil
+}
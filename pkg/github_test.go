@@ -1,7 +1,12 @@
 package pkg
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -13,19 +18,19 @@ func TestFindLoginByEmail(t *testing.T) {
 	t.Setenv(GitHubAccessTokenKey, "")
 
 	t.Run("user found with public email", func(t *testing.T) {
-		login, err := FindLoginByEmail("audreyt@audreyt.org")
+		login, err := FindLoginByEmail(context.Background(), "audreyt@audreyt.org")
 		assert.NoError(t, err)
 		assert.Equal(t, "audreyt", login)
 	})
 
 	t.Run("user found with github email", func(t *testing.T) {
-		login, err := FindLoginByEmail("20282867+yktakaha4@users.noreply.github.com")
+		login, err := FindLoginByEmail(context.Background(), "20282867+yktakaha4@users.noreply.github.com")
 		assert.NoError(t, err)
 		assert.Equal(t, "yktakaha4", login)
 	})
 
 	t.Run("user not found", func(t *testing.T) {
-		login, err := FindLoginByEmail("yktakaha4@example.com")
+		login, err := FindLoginByEmail(context.Background(), "yktakaha4@example.com")
 		assert.NoError(t, err)
 		assert.Equal(t, "", login)
 	})
@@ -42,3 +47,76 @@ func TestIsGitHubAccessTokenProvided(t *testing.T) {
 		assert.False(t, IsGitHubAccessTokenProvided())
 	})
 }
+
+func TestGetGitHubBaseUrl(t *testing.T) {
+	t.Run("is set", func(t *testing.T) {
+		t.Setenv(GitHubBaseUrlKey, "https://github.example.co.jp/")
+		assert.Equal(t, "https://github.example.co.jp", GetGitHubBaseUrl())
+		assert.True(t, IsGitHubEnterprise())
+	})
+
+	t.Run("is not set", func(t *testing.T) {
+		t.Setenv(GitHubBaseUrlKey, "")
+		assert.Equal(t, "https://github.com", GetGitHubBaseUrl())
+		assert.False(t, IsGitHubEnterprise())
+	})
+}
+
+func TestFindLoginsByCommits(t *testing.T) {
+	hashByEmail := map[string]string{
+		"alice@example.com": "1111111111111111111111111111111111111111",
+		"bob@example.com":   "2222222222222222222222222222222222222222",
+	}
+
+	t.Run("graphql", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/graphql", r.URL.Path)
+			assert.Equal(t, "Bearer dummy-github-token", r.Header.Get("Authorization"))
+
+			var body struct {
+				Query     string            `json:"query"`
+				Variables map[string]string `json:"variables"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, map[string]string{"owner": "yktakaha4", "name": "kunitori"}, body.Variables)
+			assert.True(t, strings.Contains(body.Query, `c0: object(oid: "1111111111111111111111111111111111111111")`))
+			assert.True(t, strings.Contains(body.Query, `c1: object(oid: "2222222222222222222222222222222222222222")`))
+
+			_, _ = w.Write([]byte(`{"data":{"repository":{"c0":{"author":{"user":{"login":"alice"}}},"c1":{"author":{"user":null}}}}}`))
+		}))
+		defer server.Close()
+
+		t.Setenv(GitHubBaseUrlKey, server.URL)
+		t.Setenv(GitHubAccessTokenKey, "dummy-github-token")
+
+		logins, err := FindLoginsByCommits(context.Background(), server.URL+"/yktakaha4/kunitori", hashByEmail)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"alice@example.com": "alice"}, logins)
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/yktakaha4/kunitori/commits/1111111111111111111111111111111111111111":
+				_, _ = w.Write([]byte(`{"sha":"1111111111111111111111111111111111111111","author":{"login":"alice"}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			}
+		}))
+		defer server.Close()
+
+		t.Setenv(GitHubBaseUrlKey, server.URL)
+		t.Setenv(GitHubAccessTokenKey, "")
+
+		logins, err := FindLoginsByCommits(context.Background(), server.URL+"/yktakaha4/kunitori.git", hashByEmail)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"alice@example.com": "alice"}, logins)
+	})
+
+	t.Run("not a github repository", func(t *testing.T) {
+		logins, err := FindLoginsByCommits(context.Background(), "/usr/home/repos", hashByEmail)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{}, logins)
+	})
+}
@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"fmt"
+)
+
+const (
+	LangEn = "en"
+	LangJa = "ja"
+)
+
+// Messages are the texts of the chart in each language. Texts missing in a language are shown in LangEn.
+// {0}, {1}, ... in texts are replaced with arguments by the chart.
+var Messages = map[string]map[string]string{
+	LangEn: {
+		"title":             "Kunitori",
+		"revision":          "Revision",
+		"totalLines":        "Total lines",
+		"committedAt":       "Committed at",
+		"repository":        "Repository",
+		"generated":         "Generated",
+		"weighting":         "Weighting",
+		"commit":            "Commit",
+		"filter":            "Filter",
+		"area":              "Area",
+		"rank":              "Rank",
+		"author":            "Author",
+		"team":              "Team",
+		"owner":             "Owner",
+		"lines":             "Lines",
+		"percentage":        "Percentage",
+		"authoredDeclared":  "Authored / Declared",
+		"unmaintainedFiles": "Unmaintained files",
+		"linesOf":           "Lines of {0}",
+		"directory":         "Directory",
+		"file":              "File",
+		"survivalTitle":     "Surviving lines by the period they were added in",
+		"survivalUntil":     "until {0}",
+		"weighted":          "Lines weighted by recency. A line counts half every {0} days older than the commit.",
+		"unweighted":        "Every line counts equally.",
+		"noCommits":         "No commits found.",
+	},
+	LangJa: {
+		"title":             "国盗り",
+		"revision":          "リビジョン",
+		"totalLines":        "総行数",
+		"committedAt":       "コミット日時",
+		"repository":        "リポジトリ",
+		"generated":         "生成日時",
+		"weighting":         "重み付け",
+		"commit":            "コミット",
+		"filter":            "フィルタ",
+		"area":              "地域",
+		"rank":              "順位",
+		"author":            "作者",
+		"team":              "チーム",
+		"owner":             "オーナー",
+		"lines":             "行数",
+		"percentage":        "割合",
+		"authoredDeclared":  "執筆 / 宣言",
+		"unmaintainedFiles": "保守されていないファイル",
+		"linesOf":           "{0} の行",
+		"directory":         "ディレクトリ",
+		"file":              "ファイル",
+		"survivalTitle":     "追加された期間ごとの残存行数",
+		"survivalUntil":     "{0} まで",
+		"weighted":          "新しい行ほど重く数えます。コミットより {0} 日古くなるごとに行の重みは半分になります。",
+		"unweighted":        "すべての行を同じ重みで数えます。",
+		"noCommits":         "コミットが見つかりません。",
+	},
+}
+
+// ValidateLang returns an error unless the chart can be shown in lang.
+func ValidateLang(lang string) error {
+	if _, ok := Messages[lang]; !ok {
+		return fmt.Errorf("unknown lang: lang=%v", lang)
+	}
+	return nil
+}
+
+// langOf returns the language of generateResult. Results generated before languages are shown in LangEn.
+func langOf(generateResult *GenerateResult) string {
+	if generateResult.Lang == "" {
+		return LangEn
+	}
+	return generateResult.Lang
+}
+
+// messagesOf returns the texts shown in lang, filled with LangEn.
+func messagesOf(lang string) map[string]string {
+	messages := map[string]string{}
+	for key, message := range Messages[LangEn] {
+		messages[key] = message
+	}
+	for key, message := range Messages[lang] {
+		messages[key] = message
+	}
+	return messages
+}
+
+// areaNamesOf returns the names of areas of region shown in lang, or an empty map if the region is unknown.
+func areaNamesOf(region string, lang string) map[string]string {
+	names := map[string]string{}
+	areaInfo, err := GetAreaInfo(region)
+	if err != nil {
+		return names
+	}
+	for _, area := range areaInfo.Areas {
+		names[area.Name] = areaInfo.DisplayName(area.Name, lang)
+	}
+	return names
+}
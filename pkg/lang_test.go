@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	for lang, messages := range Messages {
+		for key := range Messages[LangEn] {
+			assert.NotEmpty(t, messages[key], "lang=%v, key=%v", lang, key)
+		}
+		for key := range messages {
+			assert.Contains(t, Messages[LangEn], key, "lang=%v", lang)
+		}
+	}
+}
+
+func TestValidateLang(t *testing.T) {
+	assert.NoError(t, ValidateLang(LangEn))
+	assert.NoError(t, ValidateLang(LangJa))
+	assert.Error(t, ValidateLang(""))
+	assert.Error(t, ValidateLang("xx"))
+}
+
+func TestMessagesOf(t *testing.T) {
+	assert.Equal(t, "総行数", messagesOf(LangJa)["totalLines"])
+	assert.Equal(t, "Total lines", messagesOf(LangEn)["totalLines"])
+	assert.Equal(t, Messages[LangEn], messagesOf("xx"))
+}
+
+func TestAreaNamesOf(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"Area30": "エリア30",
+		"Area20": "エリア20",
+		"Area10": "エリア10",
+	}, areaNamesOf("__TEST", LangJa))
+	assert.Equal(t, map[string]string{
+		"Area30": "Area30",
+		"Area20": "Area20",
+		"Area10": "Area10",
+	}, areaNamesOf("__TEST", LangEn))
+	assert.Empty(t, areaNamesOf("XX", LangJa))
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Logger receives all messages of this package. Nothing is printed unless a Logger is injected.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+// NewNopLogger returns a Logger discarding all messages.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+type writerLogger struct {
+	mu     sync.Mutex
+	writer io.Writer
+	level  LogLevel
+}
+
+// NewWriterLogger returns a Logger writing messages at or above the level to the writer, one message per line.
+// Debug messages are prefixed with time, and warnings with "warning: ".
+func NewWriterLogger(writer io.Writer, level LogLevel) Logger {
+	return &writerLogger{
+		writer: writer,
+		level:  level,
+	}
+}
+
+func (l *writerLogger) Debugf(format string, args ...interface{}) {
+	l.write(LogLevelDebug, time.Now().Format("2006/01/02 15:04:05 "), format, args...)
+}
+
+func (l *writerLogger) Infof(format string, args ...interface{}) {
+	l.write(LogLevelInfo, "", format, args...)
+}
+
+func (l *writerLogger) Warnf(format string, args ...interface{}) {
+	l.write(LogLevelWarn, "warning: ", format, args...)
+}
+
+func (l *writerLogger) write(level LogLevel, prefix string, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = fmt.Fprintln(l.writer, prefix+fmt.Sprintf(format, args...))
+}
+
+type loggerContextKey struct{}
+
+// WithLogger returns a context carrying the logger, which functions of this package taking the context write to.
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger carried by the context, or a Logger discarding all messages.
+func LoggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+	return nopLogger{}
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewWriterLogger(t *testing.T) {
+	testCases := []struct {
+		level LogLevel
+		lines []string
+	}{
+		{
+			level: LogLevelDebug,
+			lines: []string{"debug: value=1", "info: value=2", "warning: warn: value=3"},
+		},
+		{
+			level: LogLevelInfo,
+			lines: []string{"info: value=2", "warning: warn: value=3"},
+		},
+		{
+			level: LogLevelWarn,
+			lines: []string{"warning: warn: value=3"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(strings.Join(testCase.lines, ","), func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			logger := NewWriterLogger(buf, testCase.level)
+
+			logger.Debugf("debug: value=%v", 1)
+			logger.Infof("info: value=%v", 2)
+			logger.Warnf("warn: value=%v", 3)
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			assert.Equal(t, len(testCase.lines), len(lines))
+			for index, line := range lines {
+				assert.True(t, strings.HasSuffix(line, testCase.lines[index]), line)
+			}
+		})
+	}
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	t.Run("not set", func(t *testing.T) {
+		assert.Equal(t, NewNopLogger(), LoggerFromContext(context.Background()))
+	})
+
+	t.Run("set", func(t *testing.T) {
+		logger := NewWriterLogger(bytes.NewBufferString(""), LogLevelInfo)
+		ctx := WithLogger(context.Background(), logger)
+		assert.Equal(t, logger, LoggerFromContext(ctx))
+	})
+}
@@ -0,0 +1,274 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type LoginOptions struct {
+	// CachePath is the file to persist resolved logins across runs.
+	CachePath string
+	// NegativeCacheTTL is how long an email without a matching login is kept in the cache.
+	NegativeCacheTTL time.Duration
+	// OverridesPath is a file of email=login lines that take precedence over any lookup.
+	OverridesPath string
+}
+
+type LoginCacheEntry struct {
+	Login      string    `json:"login"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+type LoginCache struct {
+	Entries map[string]LoginCacheEntry `json:"entries"`
+}
+
+func NewLoginCache() *LoginCache {
+	return &LoginCache{
+		Entries: map[string]LoginCacheEntry{},
+	}
+}
+
+func LoadLoginCache(path string) (*LoginCache, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewLoginCache(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cache := NewLoginCache()
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login cache: path=%v, err=%w", path, err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = map[string]LoginCacheEntry{}
+	}
+
+	return cache, nil
+}
+
+func (c *LoginCache) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func(name string) {
+		_ = os.Remove(name)
+	}(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Get returns the cached login of the email on the GitHub instance of baseUrl.
+// Negative results, which have an empty login, are only returned while they are younger than negativeTTL.
+func (c *LoginCache) Get(baseUrl string, email string, negativeTTL time.Duration, now time.Time) (string, bool) {
+	entry, ok := c.Entries[loginCacheKey(baseUrl, email)]
+	if !ok {
+		return "", false
+	}
+	if entry.Login == "" && now.Sub(entry.ResolvedAt) >= negativeTTL {
+		return "", false
+	}
+	return entry.Login, true
+}
+
+func (c *LoginCache) Set(baseUrl string, email string, login string, now time.Time) {
+	c.Entries[loginCacheKey(baseUrl, email)] = LoginCacheEntry{
+		Login:      login,
+		ResolvedAt: now.UTC(),
+	}
+}
+
+// loginCacheKey keys logins by the GitHub instance too,
+// since the same email is a different account, or none, on github.com and on GitHub Enterprise Server.
+func loginCacheKey(baseUrl string, email string) string {
+	return fmt.Sprintf("%v %v", baseUrl, email)
+}
+
+// LoadLoginOverrides reads email=login lines. Blank lines and lines starting with # are ignored.
+func LoadLoginOverrides(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	overrides := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid login override: path=%v, line=%v", path, lineNumber)
+		}
+		overrides[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+type gitHubLoginResolver struct {
+	repositoryUrl string
+	options       *LoginOptions
+	logins        map[string]string
+	overrides     map[string]string
+	cache         *LoginCache
+}
+
+func newGitHubLoginResolver(ctx context.Context, repositoryUrl string, options *LoginOptions) (*gitHubLoginResolver, error) {
+	logger := LoggerFromContext(ctx)
+
+	resolver := &gitHubLoginResolver{
+		repositoryUrl: repositoryUrl,
+		options:       options,
+		logins:        map[string]string{},
+		overrides:     map[string]string{},
+	}
+	if options == nil {
+		return resolver, nil
+	}
+
+	if options.OverridesPath != "" {
+		overrides, err := LoadLoginOverrides(options.OverridesPath)
+		if err != nil {
+			return nil, err
+		}
+		resolver.overrides = overrides
+
+		logger.Debugf("load login overrides: path=%v, count=%v", options.OverridesPath, len(overrides))
+	}
+
+	if options.CachePath != "" {
+		cache, err := LoadLoginCache(options.CachePath)
+		if err != nil {
+			return nil, err
+		}
+		resolver.cache = cache
+
+		logger.Debugf("load login cache: path=%v, count=%v", options.CachePath, len(cache.Entries))
+	}
+
+	return resolver, nil
+}
+
+func (r *gitHubLoginResolver) login(email string) string {
+	return r.logins[email]
+}
+
+func (r *gitHubLoginResolver) lookup(email string) bool {
+	if _, ok := r.logins[email]; ok {
+		return true
+	}
+	if login, ok := r.overrides[strings.ToLower(email)]; ok {
+		r.logins[email] = login
+		return true
+	}
+	if r.cache != nil {
+		if login, ok := r.cache.Get(GetGitHubBaseUrl(), email, r.options.NegativeCacheTTL, time.Now()); ok {
+			r.logins[email] = login
+			return true
+		}
+	}
+	return false
+}
+
+func (r *gitHubLoginResolver) store(email string, login string) {
+	r.logins[email] = login
+	// placeholder logins given while requests are skipped must not be credited to authors by later runs
+	if r.cache != nil && os.Getenv(KunitoriSkipRequestGitHubApi) != "yes" {
+		r.cache.Set(GetGitHubBaseUrl(), email, login, time.Now())
+	}
+}
+
+// resolve looks up logins of all authors in the results.
+// Logins are resolved from the authors' commits first, and user search by email is the fallback.
+func (r *gitHubLoginResolver) resolve(ctx context.Context, results []*CountLinesResult) error {
+	logger := LoggerFromContext(ctx)
+
+	hashByEmail := map[string]string{}
+	for _, result := range results {
+		for email := range result.LinesByAuthor {
+			if r.lookup(email) || email == "" || IsGitHubNoReplyEmail(email) {
+				continue
+			}
+			if hash := result.HashByAuthor[email]; hash != "" {
+				hashByEmail[email] = hash
+			}
+		}
+	}
+
+	if len(hashByEmail) > 0 && GetSource(r.repositoryUrl) == "github" {
+		logins, err := FindLoginsByCommits(ctx, r.repositoryUrl, hashByEmail)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		} else if err != nil {
+			logger.Warnf("failed to find logins by commits: err=%v", err)
+		}
+		for email, login := range logins {
+			r.store(email, login)
+		}
+	}
+
+	for _, result := range results {
+		for email := range result.LinesByAuthor {
+			if r.lookup(email) {
+				continue
+			}
+
+			login, err := FindLoginByEmail(ctx, email)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			} else if err != nil {
+				// keep the run going without the login, and do not cache it so that the next run retries
+				logger.Warnf("failed to find login by email: email=%v, err=%v", email, err)
+				r.logins[email] = ""
+				continue
+			}
+			r.store(email, login)
+		}
+	}
+
+	return nil
+}
+
+func (r *gitHubLoginResolver) save(ctx context.Context) error {
+	if r.cache == nil {
+		return nil
+	}
+
+	LoggerFromContext(ctx).Debugf("save login cache: path=%v, count=%v", r.options.CachePath, len(r.cache.Entries))
+	return r.cache.Save(r.options.CachePath)
+}
@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoginCache(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "logins.json")
+
+	cache, err := LoadLoginCache(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(cache.Entries))
+
+	cache.Set(GitHubDefaultBaseUrl, "alice@example.com", "alice", now.Add(-time.Hour*24*365))
+	cache.Set(GitHubDefaultBaseUrl, "bob@example.com", "", now.Add(-time.Hour*24*8))
+	cache.Set(GitHubDefaultBaseUrl, "carol@example.com", "", now.Add(-time.Hour*24))
+
+	assert.NoError(t, cache.Save(path))
+
+	loaded, err := LoadLoginCache(path)
+	assert.NoError(t, err)
+	assert.Equal(t, cache, loaded)
+
+	testCases := []struct {
+		email string
+		login string
+		ok    bool
+	}{
+		{email: "alice@example.com", login: "alice", ok: true},
+		{email: "bob@example.com", login: "", ok: false},
+		{email: "carol@example.com", login: "", ok: true},
+		{email: "dave@example.com", login: "", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.email, func(t *testing.T) {
+			login, ok := loaded.Get(GitHubDefaultBaseUrl, testCase.email, time.Hour*24*7, now)
+			assert.Equal(t, testCase.login, login)
+			assert.Equal(t, testCase.ok, ok)
+		})
+	}
+
+	_, ok := loaded.Get("https://github.example.co.jp", "alice@example.com", time.Hour*24*7, now)
+	assert.False(t, ok)
+}
+
+func TestLoadLoginOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.txt")
+
+	t.Run("valid", func(t *testing.T) {
+		err := os.WriteFile(path, []byte("# email=login\n\nAlice@Example.com = alice\nbob@example.com=\n"), 0644)
+		assert.NoError(t, err)
+
+		overrides, err := LoadLoginOverrides(path)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"alice@example.com": "alice",
+			"bob@example.com":   "",
+		}, overrides)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		err := os.WriteFile(path, []byte("alice@example.com\n"), 0644)
+		assert.NoError(t, err)
+
+		_, err = LoadLoginOverrides(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestGitHubLoginResolver(t *testing.T) {
+	t.Setenv(KunitoriSkipRequestGitHubApi, "yes")
+
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "logins.json")
+	overridesPath := filepath.Join(dir, "overrides.txt")
+
+	cache := NewLoginCache()
+	cache.Set(GitHubDefaultBaseUrl, "cached@example.com", "cached", time.Now())
+	assert.NoError(t, cache.Save(cachePath))
+	assert.NoError(t, os.WriteFile(overridesPath, []byte("overridden@example.com=overridden\n"), 0644))
+
+	resolver, err := newGitHubLoginResolver(context.Background(), "/usr/home/repos", &LoginOptions{
+		CachePath:        cachePath,
+		NegativeCacheTTL: time.Hour,
+		OverridesPath:    overridesPath,
+	})
+	assert.NoError(t, err)
+
+	err = resolver.resolve(context.Background(), []*CountLinesResult{
+		{
+			LinesByAuthor: map[string]int{
+				"cached@example.com":     1,
+				"overridden@example.com": 1,
+				"searched@example.com":   1,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "cached", resolver.login("cached@example.com"))
+	assert.Equal(t, "overridden", resolver.login("overridden@example.com"))
+	assert.Equal(t, "kunitori", resolver.login("searched@example.com"))
+
+	assert.NoError(t, resolver.save(context.Background()))
+
+	saved, err := LoadLoginCache(cachePath)
+	assert.NoError(t, err)
+	assert.NotContains(t, saved.Entries, loginCacheKey(GitHubDefaultBaseUrl, "searched@example.com"))
+	assert.Equal(t, "cached", saved.Entries[loginCacheKey(GitHubDefaultBaseUrl, "cached@example.com")].Login)
+	assert.NotContains(t, saved.Entries, loginCacheKey(GitHubDefaultBaseUrl, "overridden@example.com"))
+	assert.Equal(t, 1, len(saved.Entries))
+}
+
+func TestGitHubLoginResolver__enterprise(t *testing.T) {
+	t.Setenv(KunitoriSkipRequestGitHubApi, "yes")
+	t.Setenv(GitHubBaseUrlKey, "https://github.example.co.jp")
+
+	cachePath := filepath.Join(t.TempDir(), "logins.json")
+	cache := NewLoginCache()
+	cache.Set(GitHubDefaultBaseUrl, "alice@example.com", "alice", time.Now())
+	cache.Set("https://github.example.co.jp", "bob@example.com", "bob-enterprise", time.Now())
+	assert.NoError(t, cache.Save(cachePath))
+
+	resolver, err := newGitHubLoginResolver(context.Background(), "/usr/home/repos", &LoginOptions{
+		CachePath: cachePath,
+	})
+	assert.NoError(t, err)
+
+	err = resolver.resolve(context.Background(), []*CountLinesResult{
+		{
+			LinesByAuthor: map[string]int{
+				"alice@example.com": 1,
+				"bob@example.com":   1,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "kunitori", resolver.login("alice@example.com"))
+	assert.Equal(t, "bob-enterprise", resolver.login("bob@example.com"))
+}
+
+func TestGitHubLoginResolver__degrade(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	t.Setenv(GitHubBaseUrlKey, server.URL)
+	t.Setenv(GitHubAccessTokenKey, "")
+
+	cachePath := filepath.Join(t.TempDir(), "logins.json")
+	resolver, err := newGitHubLoginResolver(context.Background(), server.URL+"/yktakaha4/kunitori", &LoginOptions{
+		CachePath: cachePath,
+	})
+	assert.NoError(t, err)
+
+	err = resolver.resolve(context.Background(), []*CountLinesResult{
+		{
+			LinesByAuthor: map[string]int{"alice@example.com": 1},
+			HashByAuthor:  map[string]string{"alice@example.com": "1111111111111111111111111111111111111111"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "", resolver.login("alice@example.com"))
+
+	assert.NoError(t, resolver.save(context.Background()))
+
+	saved, err := LoadLoginCache(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(saved.Entries))
+}
@@ -0,0 +1,417 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dlclark/regexp2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergeGenerateResults combines results generated with the same settings.
+// Results of the same repository, e.g. sharded by filter, are combined commit by commit.
+// Results of different repositories are unioned by date: at each committed date, the latest commit of each repository
+// at the date is taken, lines of the same filter are summed by author, and areas are allocated again.
+func MergeGenerateResults(ctx context.Context, generateResults []*GenerateResult) (*GenerateResult, error) {
+	logger := LoggerFromContext(ctx)
+
+	if len(generateResults) == 0 {
+		return nil, errors.New("no generate result to merge")
+	}
+
+	first := generateResults[0]
+	for _, generateResult := range generateResults[1:] {
+		if generateResult.Region != first.Region ||
+			generateResult.Allocation != first.Allocation ||
+			generateResult.GroupBy != first.GroupBy ||
+			generateResult.Ownership != first.Ownership ||
+			generateResult.Weighting != first.Weighting {
+			return nil, fmt.Errorf(
+				"cannot merge results generated with different settings: repositories=%v, %v",
+				first.Repository,
+				generateResult.Repository,
+			)
+		}
+	}
+
+	repositories := make([]string, 0)
+	resultsByRepository := map[string][]*GenerateResult{}
+	for _, generateResult := range generateResults {
+		if _, ok := resultsByRepository[generateResult.Repository]; !ok {
+			repositories = append(repositories, generateResult.Repository)
+		}
+		resultsByRepository[generateResult.Repository] = append(resultsByRepository[generateResult.Repository], generateResult)
+	}
+
+	merged := make([]*GenerateResult, 0, len(repositories))
+	for _, repository := range repositories {
+		result, err := mergeSameRepository(resultsByRepository[repository])
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, result)
+	}
+
+	result := merged[0]
+	if len(merged) > 1 {
+		logger.Infof("union repositories by date: repositories=%v", len(merged))
+		for _, repositoryResult := range merged {
+			if repositoryResult.Survival != nil {
+				logger.Warnf("survival cannot be merged across repositories: repository=%v", repositoryResult.Repository)
+				break
+			}
+		}
+
+		var err error
+		result, err = unionRepositoriesByDate(ctx, merged)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// colours are given again since the results colour their own authors only.
+	// colours the results already have are kept, so that authors keep theirs and -colors given to generate are respected.
+	palette := first.Palette
+	if palette == "" {
+		palette = PaletteRank
+	}
+	colors := map[string]string{}
+	for _, generateResult := range generateResults {
+		for author, color := range generateResult.Colors {
+			if _, ok := colors[author]; !ok {
+				colors[author] = color
+			}
+		}
+	}
+	err := ColorAuthors(result, palette, colors)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// mergeSameRepository combines line counts of each commit. The same filter of the same commit must not be counted twice,
+// and every result must have the same commits, otherwise commits would be counted with some of the filters only.
+func mergeSameRepository(generateResults []*GenerateResult) (*GenerateResult, error) {
+	hashes := commitHashesOf(generateResults[0])
+	for _, generateResult := range generateResults[1:] {
+		otherHashes := commitHashesOf(generateResult)
+		if len(otherHashes) != len(hashes) {
+			return nil, fmt.Errorf(
+				"cannot merge results of different commits: repository=%v, commits=%v, %v",
+				generateResult.Repository,
+				len(hashes),
+				len(otherHashes),
+			)
+		}
+		for hash := range otherHashes {
+			if !hashes[hash] {
+				return nil, fmt.Errorf("cannot merge results of different commits: repository=%v, hash=%v", generateResult.Repository, hash)
+			}
+		}
+	}
+
+	merged := *generateResults[0]
+	merged.Commits = make([]GenerateResultCommit, 0)
+
+	commitIndexByHash := map[string]int{}
+	for _, generateResult := range generateResults {
+		if merged.GeneratedAt.Before(generateResult.GeneratedAt) {
+			merged.GeneratedAt = generateResult.GeneratedAt
+		}
+
+		for _, commit := range generateResult.Commits {
+			index, ok := commitIndexByHash[commit.Hash]
+			if !ok {
+				commitIndexByHash[commit.Hash] = len(merged.Commits)
+				merged.Commits = append(merged.Commits, GenerateResultCommit{
+					Hash:        commit.Hash,
+					CommittedAt: commit.CommittedAt,
+					LineCounts:  append([]GenerateResultCommitLineCount{}, commit.LineCounts...),
+				})
+				continue
+			}
+
+			for _, lineCount := range commit.LineCounts {
+				for _, mergedLineCount := range merged.Commits[index].LineCounts {
+					if mergedLineCount.FilterRegex == lineCount.FilterRegex {
+						return nil, fmt.Errorf(
+							"filter counted twice: repository=%v, hash=%v, filter=%v",
+							merged.Repository,
+							commit.Hash,
+							lineCount.FilterRegex,
+						)
+					}
+				}
+				merged.Commits[index].LineCounts = append(merged.Commits[index].LineCounts, lineCount)
+			}
+		}
+	}
+
+	sortCommitsByDate(merged.Commits)
+
+	merged.Survival = mergeSurvival(generateResults)
+
+	return &merged, nil
+}
+
+func commitHashesOf(generateResult *GenerateResult) map[string]bool {
+	hashes := map[string]bool{}
+	for _, commit := range generateResult.Commits {
+		hashes[commit.Hash] = true
+	}
+	return hashes
+}
+
+// mergeSurvival combines survival of each filter. It is dropped unless every result counted survival at the same snapshots.
+func mergeSurvival(generateResults []*GenerateResult) *GenerateResultSurvival {
+	first := generateResults[0].Survival
+	if first == nil {
+		return nil
+	}
+
+	survival := &GenerateResultSurvival{
+		Periods:   first.Periods,
+		Snapshots: first.Snapshots,
+		Filters:   make([]GenerateResultSurvivalFilter, 0),
+	}
+	found := map[string]bool{}
+	for _, generateResult := range generateResults {
+		if generateResult.Survival == nil ||
+			!equalTimes(generateResult.Survival.Periods, first.Periods) ||
+			!equalTimes(generateResult.Survival.Snapshots, first.Snapshots) {
+			return nil
+		}
+		for _, filter := range generateResult.Survival.Filters {
+			if found[filter.FilterRegex] {
+				continue
+			}
+			found[filter.FilterRegex] = true
+			survival.Filters = append(survival.Filters, filter)
+		}
+	}
+
+	return survival
+}
+
+func equalTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortCommitsByDate sorts commits from the latest as Generate does.
+func sortCommitsByDate(commits []GenerateResultCommit) {
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].CommittedAt.After(commits[j].CommittedAt)
+	})
+}
+
+func unionRepositoriesByDate(ctx context.Context, generateResults []*GenerateResult) (*GenerateResult, error) {
+	first := generateResults[0]
+
+	areaInfo, err := GetAreaInfo(first.Region)
+	if err != nil {
+		return nil, err
+	}
+	allocator, err := GetAllocator(first.Allocation)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := make([]string, 0)
+	dates := make([]time.Time, 0)
+	generatedAt := first.GeneratedAt
+	for _, generateResult := range generateResults {
+		repositories = append(repositories, generateResult.Repository)
+		for _, commit := range generateResult.Commits {
+			dates = append(dates, commit.CommittedAt)
+		}
+		if generatedAt.Before(generateResult.GeneratedAt) {
+			generatedAt = generateResult.GeneratedAt
+		}
+	}
+
+	sort.SliceStable(dates, func(i, j int) bool {
+		return dates[i].After(dates[j])
+	})
+
+	// the source is unknown since the joined repositories and hashes cannot be linked
+	merged := &GenerateResult{
+		SchemaVersion: GenerateResultSchemaVersion,
+		Repository:    strings.Join(repositories, ", "),
+		Source:        "unknown",
+		GitHubUrl:     first.GitHubUrl,
+		Region:        areaInfo.Region,
+		Allocation:    first.Allocation,
+		GroupBy:       first.GroupBy,
+		Ownership:     first.Ownership,
+		Weighting:     first.Weighting,
+		Lang:          first.Lang,
+		GeneratedAt:   generatedAt,
+		Commits:       make([]GenerateResultCommit, 0),
+	}
+
+	// allocated from the latest as Generate does, so that the stable allocation keeps areas over time
+	for index, date := range dates {
+		if index > 0 && dates[index-1].Equal(date) {
+			continue
+		}
+
+		hashes := make([]string, 0)
+		filters := make([]string, 0)
+		lineCountsByFilter := map[string][]GenerateResultCommitLineCount{}
+		for _, generateResult := range generateResults {
+			commit := latestCommitAt(generateResult.Commits, date)
+			if commit == nil {
+				continue
+			}
+			hashes = append(hashes, commit.Hash)
+			for _, lineCount := range commit.LineCounts {
+				if _, ok := lineCountsByFilter[lineCount.FilterRegex]; !ok {
+					filters = append(filters, lineCount.FilterRegex)
+				}
+				lineCountsByFilter[lineCount.FilterRegex] = append(lineCountsByFilter[lineCount.FilterRegex], lineCount)
+			}
+		}
+
+		lineCounts := make([]GenerateResultCommitLineCount, 0, len(filters))
+		for _, filter := range filters {
+			lineCount, err := sumLineCounts(ctx, allocator, areaInfo, filter, lineCountsByFilter[filter], first.Weighting.Method == WeightingRecency)
+			if err != nil {
+				return nil, err
+			}
+			lineCounts = append(lineCounts, lineCount)
+		}
+
+		merged.Commits = append(merged.Commits, GenerateResultCommit{
+			Hash:        strings.Join(hashes, ","),
+			CommittedAt: date,
+			LineCounts:  lineCounts,
+		})
+	}
+
+	return merged, nil
+}
+
+// latestCommitAt returns the latest of commits committed at or before date, or nil if there is none.
+func latestCommitAt(commits []GenerateResultCommit, date time.Time) *GenerateResultCommit {
+	var latest *GenerateResultCommit
+	for i, commit := range commits {
+		if commit.CommittedAt.After(date) {
+			continue
+		}
+		if latest == nil || commit.CommittedAt.After(latest.CommittedAt) {
+			latest = &commits[i]
+		}
+	}
+	return latest
+}
+
+// sumLineCounts sums lines of each author of lineCounts and allocates areas to them.
+// Details and ownership diffs are dropped since paths of different repositories cannot be told apart.
+func sumLineCounts(
+	ctx context.Context,
+	allocator Allocator,
+	areaInfo *AreaInfo,
+	filterRegex string,
+	lineCounts []GenerateResultCommitLineCount,
+	weighted bool,
+) (GenerateResultCommitLineCount, error) {
+	filter, err := regexp2.Compile(filterRegex, 0)
+	if err != nil {
+		return GenerateResultCommitLineCount{}, err
+	}
+
+	result := &CountLinesResult{
+		Filter:        filter,
+		LinesByAuthor: map[string]int{},
+		NameByAuthor:  map[string]string{},
+	}
+	if weighted {
+		result.ScoreByAuthor = map[string]float64{}
+	}
+
+	fileCount := 0
+	authorByEmail := map[string]GenerateResultCommitLineCountAuthor{}
+	for _, lineCount := range lineCounts {
+		fileCount += lineCount.FileCount
+		for _, author := range lineCount.Authors {
+			authorByEmail[author.Email] = sumAuthors(authorByEmail[author.Email], author)
+		}
+	}
+	for email, author := range authorByEmail {
+		result.LinesByAuthor[email] = author.LineCount
+		result.NameByAuthor[email] = author.Name
+		if weighted {
+			result.ScoreByAuthor[email] = author.Score
+		}
+	}
+
+	newAuthor := func(email string, lineCount int, rank int) GenerateResultCommitLineCountAuthor {
+		author := authorByEmail[email]
+		author.Rank = rank
+		return author
+	}
+
+	areas, authors, err := allocateLineCount(ctx, allocator, areaInfo, result, newAuthor)
+	if err != nil {
+		return GenerateResultCommitLineCount{}, err
+	}
+
+	return GenerateResultCommitLineCount{
+		FilterRegex: filterRegex,
+		FileCount:   fileCount,
+		Areas:       areas,
+		Authors:     authors,
+	}, nil
+}
+
+// sumAuthors adds lines of author to sum. Members of teams are summed by their emails.
+func sumAuthors(sum GenerateResultCommitLineCountAuthor, author GenerateResultCommitLineCountAuthor) GenerateResultCommitLineCountAuthor {
+	if sum.Email == "" {
+		sum.Email = author.Email
+	}
+	if sum.Name == "" {
+		sum.Name = author.Name
+	}
+	if sum.GitHubLogin == "" {
+		sum.GitHubLogin = author.GitHubLogin
+	}
+	sum.LineCount += author.LineCount
+	sum.Score += author.Score
+
+	if len(author.Members) > 0 {
+		members := append([]GenerateResultCommitLineCountAuthor{}, sum.Members...)
+		for _, member := range author.Members {
+			found := false
+			for i := range members {
+				if members[i].Email == member.Email {
+					members[i] = sumAuthors(members[i], member)
+					found = true
+					break
+				}
+			}
+			if !found {
+				members = append(members, sumAuthors(GenerateResultCommitLineCountAuthor{}, member))
+			}
+		}
+
+		sortAuthorsByWeight(members)
+		for i := range members {
+			members[i].Rank = i + 1
+		}
+		sum.Members = members
+	}
+
+	return sum
+}
@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestMergeResult(repository string, commits ...GenerateResultCommit) *GenerateResult {
+	generateResult := newTestExportResult()
+	generateResult.Repository = repository
+	generateResult.Commits = commits
+	return generateResult
+}
+
+func newTestMergeCommit(hash string, committedAt time.Time, filterRegex string, authors ...GenerateResultCommitLineCountAuthor) GenerateResultCommit {
+	return GenerateResultCommit{
+		Hash:        hash,
+		CommittedAt: committedAt,
+		LineCounts: []GenerateResultCommitLineCount{
+			{
+				FilterRegex: filterRegex,
+				FileCount:   1,
+				Areas:       []GenerateResultCommitLineCountArea{},
+				Authors:     authors,
+			},
+		},
+	}
+}
+
+func TestMergeGenerateResults__sameRepository(t *testing.T) {
+	jan := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	alice := GenerateResultCommitLineCountAuthor{Email: "alice@example.com", LineCount: 10, Rank: 1}
+
+	goShard := newTestMergeResult(
+		"https://github.com/yktakaha4/kunitori",
+		newTestMergeCommit("b", feb, "\\.go$", alice),
+		newTestMergeCommit("a", jan, "\\.go$", alice),
+	)
+	mdShard := newTestMergeResult(
+		"https://github.com/yktakaha4/kunitori",
+		newTestMergeCommit("a", jan, "\\.md$", alice),
+		newTestMergeCommit("b", feb, "\\.md$", alice),
+	)
+
+	merged, err := MergeGenerateResults(context.Background(), []*GenerateResult{goShard, mdShard})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/yktakaha4/kunitori", merged.Repository)
+	assert.Equal(t, 2, len(merged.Commits))
+	for i, hash := range []string{"b", "a"} {
+		assert.Equal(t, hash, merged.Commits[i].Hash)
+		assert.Equal(t, 2, len(merged.Commits[i].LineCounts))
+		assert.Equal(t, "\\.go$", merged.Commits[i].LineCounts[0].FilterRegex)
+		assert.Equal(t, "\\.md$", merged.Commits[i].LineCounts[1].FilterRegex)
+	}
+	assert.Equal(t, 1, len(goShard.Commits[0].LineCounts))
+
+	_, err = MergeGenerateResults(context.Background(), []*GenerateResult{goShard, goShard})
+	assert.Error(t, err)
+
+	partialShard := newTestMergeResult(
+		"https://github.com/yktakaha4/kunitori",
+		newTestMergeCommit("b", feb, "\\.md$", alice),
+	)
+	_, err = MergeGenerateResults(context.Background(), []*GenerateResult{goShard, partialShard})
+	assert.Error(t, err)
+
+	otherCommitShard := newTestMergeResult(
+		"https://github.com/yktakaha4/kunitori",
+		newTestMergeCommit("b", feb, "\\.md$", alice),
+		newTestMergeCommit("c", jan, "\\.md$", alice),
+	)
+	_, err = MergeGenerateResults(context.Background(), []*GenerateResult{goShard, otherCommitShard})
+	assert.Error(t, err)
+
+	otherAllocation := newTestMergeResult("https://github.com/yktakaha4/kunitori")
+	otherAllocation.Allocation = AllocationContiguous
+	_, err = MergeGenerateResults(context.Background(), []*GenerateResult{goShard, otherAllocation})
+	assert.Error(t, err)
+
+	_, err = MergeGenerateResults(context.Background(), []*GenerateResult{})
+	assert.Error(t, err)
+}
+
+func TestMergeGenerateResults__unionRepositoriesByDate(t *testing.T) {
+	jan := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mid := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	frontend := newTestMergeResult(
+		"https://github.com/yktakaha4/frontend",
+		newTestMergeCommit("f2", feb, ".+",
+			GenerateResultCommitLineCountAuthor{Email: "alice@example.com", Name: "Alice", LineCount: 30, Rank: 1},
+		),
+		newTestMergeCommit("f1", jan, ".+",
+			GenerateResultCommitLineCountAuthor{Email: "alice@example.com", Name: "Alice", LineCount: 10, Rank: 1},
+		),
+	)
+	backend := newTestMergeResult(
+		"https://github.com/yktakaha4/backend",
+		newTestMergeCommit("b1", mid, ".+",
+			GenerateResultCommitLineCountAuthor{Email: "bob@example.com", Name: "Bob", LineCount: 20, Rank: 1},
+			GenerateResultCommitLineCountAuthor{Email: "alice@example.com", GitHubLogin: "alice", LineCount: 5, Rank: 2},
+		),
+	)
+
+	merged, err := MergeGenerateResults(context.Background(), []*GenerateResult{frontend, backend})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/yktakaha4/frontend, https://github.com/yktakaha4/backend", merged.Repository)
+	assert.Equal(t, "unknown", merged.Source)
+
+	hashes := make([]string, 0)
+	for _, commit := range merged.Commits {
+		hashes = append(hashes, commit.Hash)
+	}
+	assert.Equal(t, []string{"f2,b1", "f1,b1", "f1"}, hashes)
+	assert.Equal(t, []time.Time{feb, mid, jan}, []time.Time{
+		merged.Commits[0].CommittedAt,
+		merged.Commits[1].CommittedAt,
+		merged.Commits[2].CommittedAt,
+	})
+
+	lineCount := merged.Commits[0].LineCounts[0]
+	assert.Equal(t, 2, lineCount.FileCount)
+	assert.Equal(t, []GenerateResultCommitLineCountAuthor{
+		{Email: "alice@example.com", Name: "Alice", GitHubLogin: "alice", LineCount: 35, Rank: 1},
+		{Email: "bob@example.com", Name: "Bob", LineCount: 20, Rank: 2},
+	}, lineCount.Authors)
+	totalRatio := float64(0)
+	for _, area := range lineCount.Areas {
+		totalRatio += area.Ratio
+	}
+	assert.InDelta(t, 1, totalRatio, 1e-9)
+
+	lineCount = merged.Commits[1].LineCounts[0]
+	assert.Equal(t, "bob@example.com", lineCount.Authors[0].Email)
+	assert.Equal(t, 20, lineCount.Authors[0].LineCount)
+	assert.Equal(t, 15, lineCount.Authors[1].LineCount)
+}
+
+func TestSumAuthors(t *testing.T) {
+	sum := sumAuthors(GenerateResultCommitLineCountAuthor{}, GenerateResultCommitLineCountAuthor{
+		Email:     "backend",
+		Name:      "backend",
+		LineCount: 10,
+		Members: []GenerateResultCommitLineCountAuthor{
+			{Email: "alice@example.com", LineCount: 10, Rank: 1},
+		},
+	})
+	sum = sumAuthors(sum, GenerateResultCommitLineCountAuthor{
+		Email:     "backend",
+		Name:      "backend",
+		LineCount: 25,
+		Members: []GenerateResultCommitLineCountAuthor{
+			{Email: "bob@example.com", LineCount: 20, Rank: 1},
+			{Email: "alice@example.com", LineCount: 5, Rank: 2},
+		},
+	})
+
+	assert.Equal(t, GenerateResultCommitLineCountAuthor{
+		Email:     "backend",
+		Name:      "backend",
+		LineCount: 35,
+		Members: []GenerateResultCommitLineCountAuthor{
+			{Email: "bob@example.com", LineCount: 20, Rank: 1},
+			{Email: "alice@example.com", LineCount: 15, Rank: 2},
+		},
+	}, sum)
+}
+
+func TestMergeGenerateResults__colors(t *testing.T) {
+	jan := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	alice := GenerateResultCommitLineCountAuthor{Email: "alice@example.com", LineCount: 10, Rank: 1}
+	bob := GenerateResultCommitLineCountAuthor{Email: "bob@example.com", LineCount: 5, Rank: 2}
+
+	frontend := newTestMergeResult("https://github.com/yktakaha4/frontend", newTestMergeCommit("f1", jan, ".+", alice))
+	frontend.Palette = PaletteCategorical
+	frontend.Colors = map[string]string{"alice@example.com": "#123456"}
+	backend := newTestMergeResult("https://github.com/yktakaha4/backend", newTestMergeCommit("b1", jan, ".+", alice, bob))
+	backend.Palette = PaletteCategorical
+	backend.Colors = map[string]string{"alice@example.com": "#654321", "bob@example.com": "#abcdef"}
+
+	merged, err := MergeGenerateResults(context.Background(), []*GenerateResult{frontend, backend})
+	assert.NoError(t, err)
+	assert.Equal(t, PaletteCategorical, merged.Palette)
+	assert.Equal(t, map[string]string{"alice@example.com": "#123456", "bob@example.com": "#abcdef"}, merged.Colors)
+}
@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+const (
+	FormatHtml     = "html"
+	FormatJson     = "json"
+	FormatCsv      = "csv"
+	FormatMarkdown = "markdown"
+	FormatSvg      = "svg"
+)
+
+// Formats are the output formats in the order outputs are rendered.
+var Formats = []string{FormatHtml, FormatJson, FormatCsv, FormatMarkdown, FormatSvg}
+
+// DefaultOutputNameTemplate names outputs chart.html, generate.json, authors.csv, areas.csv, kunitori.md and chart.svg.
+const DefaultOutputNameTemplate = "{{.Name}}.{{.Ext}}"
+
+// Output is a rendered file of GenerateResult.
+type Output struct {
+	Format string
+	// Name is the default base name of the file, which tells outputs of the same format apart.
+	Name string
+	Ext  string
+	Data []byte
+}
+
+// OutputNameData is the data given to output name templates.
+type OutputNameData struct {
+	Name   string
+	Ext    string
+	Format string
+	// Repository is the last path element of the repository, e.g. "kunitori".
+	Repository string
+	// Hash is the short hash of the latest commit, or empty if no commit is counted.
+	Hash string
+	// Date is the generated date in YYYYMMDD.
+	Date string
+}
+
+// RenderOutputs renders generateResult in each format. A format may render several outputs.
+// options may be nil to render with the defaults.
+func RenderOutputs(generateResult *GenerateResult, formats []string, options *RenderOptions) ([]Output, error) {
+	requested := map[string]bool{}
+	for _, format := range formats {
+		if !isKnownFormat(format) {
+			return nil, fmt.Errorf("unknown format: format=%v", format)
+		}
+		requested[format] = true
+	}
+
+	outputs := make([]Output, 0)
+	for _, format := range Formats {
+		if !requested[format] {
+			continue
+		}
+
+		switch format {
+		case FormatHtml:
+			html, err := RenderChartHtml(generateResult, options)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, Output{Format: format, Name: "chart", Ext: "html", Data: []byte(html)})
+		case FormatJson:
+			data, err := json.Marshal(generateResult)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, Output{Format: format, Name: "generate", Ext: "json", Data: data})
+		case FormatCsv:
+			authorsCsv, err := RenderAuthorsCsv(generateResult)
+			if err != nil {
+				return nil, err
+			}
+			areasCsv, err := RenderAreasCsv(generateResult)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs,
+				Output{Format: format, Name: "authors", Ext: "csv", Data: []byte(authorsCsv)},
+				Output{Format: format, Name: "areas", Ext: "csv", Data: []byte(areasCsv)},
+			)
+		case FormatMarkdown:
+			markdown, err := RenderMarkdown(generateResult)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, Output{Format: format, Name: "kunitori", Ext: "md", Data: []byte(markdown)})
+		case FormatSvg:
+			svg, err := RenderChartSvg(generateResult)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, Output{Format: format, Name: "chart", Ext: "svg", Data: []byte(svg)})
+		}
+	}
+
+	return outputs, nil
+}
+
+func isKnownFormat(format string) bool {
+	for _, known := range Formats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOutputNameTemplate parses a text/template naming outputs with OutputNameData.
+func ParseOutputNameTemplate(text string) (*template.Template, error) {
+	return template.New("name").Option("missingkey=error").Parse(text)
+}
+
+// OutputFileNames names outputs with nameTemplate. Names must be unique and must not contain directories.
+func OutputFileNames(nameTemplate *template.Template, outputs []Output, generateResult *GenerateResult) ([]string, error) {
+	repository := path.Base(strings.TrimSuffix(strings.TrimSuffix(generateResult.Repository, "/"), ".git"))
+	hash := ""
+	if len(generateResult.Commits) > 0 {
+		hash = shortHash(generateResult.Commits[0].Hash)
+	}
+
+	fileNames := make([]string, 0, len(outputs))
+	found := map[string]bool{}
+	for _, output := range outputs {
+		buf := bytes.NewBufferString("")
+		err := nameTemplate.Execute(buf, OutputNameData{
+			Name:       output.Name,
+			Ext:        output.Ext,
+			Format:     output.Format,
+			Repository: repository,
+			Hash:       hash,
+			Date:       generateResult.GeneratedAt.Format("20060102"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		fileName := buf.String()
+		if fileName == "" || strings.ContainsAny(fileName, "/\\") {
+			return nil, fmt.Errorf("invalid output name: name=%v", fileName)
+		}
+		if found[fileName] {
+			return nil, fmt.Errorf("duplicated output name: name=%v", fileName)
+		}
+		found[fileName] = true
+		fileNames = append(fileNames, fileName)
+	}
+
+	return fileNames, nil
+}
+
+// SplitFormats splits comma separated formats, e.g. "html,json".
+func SplitFormats(values []string) ([]string, error) {
+	formats := make([]string, 0)
+	for _, value := range values {
+		for _, format := range strings.Split(value, ",") {
+			format = strings.TrimSpace(format)
+			if format == "" {
+				continue
+			}
+			if !isKnownFormat(format) {
+				return nil, fmt.Errorf("unknown format: format=%v", format)
+			}
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) == 0 {
+		return nil, errors.New("no format specified")
+	}
+	return formats, nil
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRenderOutputs(t *testing.T) {
+	generateResult := newTestExportResult()
+
+	outputs, err := RenderOutputs(generateResult, []string{FormatSvg, FormatMarkdown, FormatCsv, FormatHtml, FormatJson, FormatHtml}, nil)
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, output := range outputs {
+		names = append(names, output.Name+"."+output.Ext)
+		assert.NotEmpty(t, output.Data)
+	}
+	assert.Equal(t, []string{"chart.html", "generate.json", "authors.csv", "areas.csv", "kunitori.md", "chart.svg"}, names)
+
+	_, err = RenderOutputs(generateResult, []string{"pdf"}, nil)
+	assert.Error(t, err)
+}
+
+func TestOutputFileNames(t *testing.T) {
+	generateResult := newTestExportResult()
+	outputs, err := RenderOutputs(generateResult, []string{FormatHtml, FormatCsv}, nil)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		template  string
+		fileNames []string
+		isError   bool
+	}{
+		{
+			name:      "default",
+			template:  DefaultOutputNameTemplate,
+			fileNames: []string{"chart.html", "authors.csv", "areas.csv"},
+		},
+		{
+			name:      "with repository, hash and date",
+			template:  "{{.Repository}}-{{.Hash}}-{{.Date}}-{{.Name}}.{{.Ext}}",
+			fileNames: []string{"kunitori-2fa8fa8-20230102-chart.html", "kunitori-2fa8fa8-20230102-authors.csv", "kunitori-2fa8fa8-20230102-areas.csv"},
+		},
+		{
+			name:     "duplicated",
+			template: "{{.Repository}}.{{.Ext}}",
+			isError:  true,
+		},
+		{
+			name:     "directory",
+			template: "out/{{.Name}}.{{.Ext}}",
+			isError:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			nameTemplate, err := ParseOutputNameTemplate(testCase.template)
+			assert.NoError(t, err)
+
+			fileNames, err := OutputFileNames(nameTemplate, outputs, generateResult)
+			if testCase.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.fileNames, fileNames)
+			}
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		nameTemplate, err := ParseOutputNameTemplate("{{.Unknown}}")
+		assert.NoError(t, err)
+		_, err = OutputFileNames(nameTemplate, outputs, generateResult)
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitFormats(t *testing.T) {
+	formats, err := SplitFormats([]string{"html,json", " csv ", "markdown,"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"html", "json", "csv", "markdown"}, formats)
+
+	_, err = SplitFormats([]string{"html,pdf"})
+	assert.True(t, strings.Contains(err.Error(), "pdf"))
+
+	_, err = SplitFormats([]string{","})
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ProgressPhase string
+
+const (
+	ProgressPhaseSearchCommits ProgressPhase = "searchCommits"
+	ProgressPhaseCountLines    ProgressPhase = "countLines"
+	ProgressPhaseResolveLogins ProgressPhase = "resolveLogins"
+	ProgressPhaseComplete      ProgressPhase = "complete"
+)
+
+// ProgressEvent describes how far Generate has come.
+// CommitIndex and FileIndex are 1-based, and FileIndex is 0 when counting of the commit has just started.
+type ProgressEvent struct {
+	Phase       ProgressPhase
+	CommitIndex int
+	CommitCount int
+	CommitHash  string
+	CommittedAt time.Time
+	FileIndex   int
+	FileCount   int
+	File        string
+	Elapsed     time.Duration
+	// ETA is the estimated remaining duration, and is 0 until it can be estimated.
+	ETA time.Duration
+}
+
+func (e ProgressEvent) MarshalJSON() ([]byte, error) {
+	type progressEventJson struct {
+		Phase          ProgressPhase `json:"phase"`
+		CommitIndex    int           `json:"commitIndex"`
+		CommitCount    int           `json:"commitCount"`
+		CommitHash     string        `json:"commitHash,omitempty"`
+		CommittedAt    *time.Time    `json:"committedAt,omitempty"`
+		FileIndex      int           `json:"fileIndex"`
+		FileCount      int           `json:"fileCount"`
+		File           string        `json:"file,omitempty"`
+		ElapsedSeconds float64       `json:"elapsedSeconds"`
+		EtaSeconds     float64       `json:"etaSeconds"`
+	}
+
+	var committedAt *time.Time
+	if !e.CommittedAt.IsZero() {
+		committedAt = &e.CommittedAt
+	}
+
+	return json.Marshal(progressEventJson{
+		Phase:          e.Phase,
+		CommitIndex:    e.CommitIndex,
+		CommitCount:    e.CommitCount,
+		CommitHash:     e.CommitHash,
+		CommittedAt:    committedAt,
+		FileIndex:      e.FileIndex,
+		FileCount:      e.FileCount,
+		File:           e.File,
+		ElapsedSeconds: e.Elapsed.Seconds(),
+		EtaSeconds:     e.ETA.Seconds(),
+	})
+}
+
+// progressReporter fills elapsed time and ETA of events before passing them to the callback.
+type progressReporter struct {
+	callback  func(event ProgressEvent)
+	startedAt time.Time
+	now       func() time.Time
+}
+
+func newProgressReporter(callback func(event ProgressEvent)) *progressReporter {
+	return &progressReporter{
+		callback:  callback,
+		startedAt: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (r *progressReporter) report(event ProgressEvent) {
+	if r.callback == nil {
+		return
+	}
+
+	event.Elapsed = r.now().Sub(r.startedAt)
+	if event.CommitCount > 0 && event.CommitIndex > 0 {
+		done := float64(event.CommitIndex - 1)
+		if event.FileCount > 0 {
+			done += float64(event.FileIndex) / float64(event.FileCount)
+		}
+		if done > 0 {
+			total := float64(event.Elapsed) * float64(event.CommitCount) / done
+			event.ETA = time.Duration(total) - event.Elapsed
+		}
+	}
+	if event.Phase == ProgressPhaseComplete || event.ETA < 0 {
+		event.ETA = 0
+	}
+
+	r.callback(event)
+}
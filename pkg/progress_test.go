@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestProgressReporter(t *testing.T) {
+	startedAt := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		event   ProgressEvent
+		elapsed time.Duration
+		eta     time.Duration
+	}{
+		{
+			event: ProgressEvent{
+				Phase: ProgressPhaseSearchCommits,
+			},
+			elapsed: time.Second,
+			eta:     0,
+		},
+		{
+			event: ProgressEvent{
+				Phase:       ProgressPhaseCountLines,
+				CommitIndex: 1,
+				CommitCount: 4,
+			},
+			elapsed: time.Second * 2,
+			eta:     0,
+		},
+		{
+			event: ProgressEvent{
+				Phase:       ProgressPhaseCountLines,
+				CommitIndex: 2,
+				CommitCount: 4,
+				FileIndex:   5,
+				FileCount:   10,
+			},
+			elapsed: time.Second * 30,
+			eta:     time.Second * 50,
+		},
+		{
+			event: ProgressEvent{
+				Phase:       ProgressPhaseComplete,
+				CommitIndex: 4,
+				CommitCount: 4,
+			},
+			elapsed: time.Second * 80,
+			eta:     0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(string(testCase.event.Phase), func(t *testing.T) {
+			var reported ProgressEvent
+			reporter := newProgressReporter(func(event ProgressEvent) {
+				reported = event
+			})
+			reporter.startedAt = startedAt
+			reporter.now = func() time.Time {
+				return startedAt.Add(testCase.elapsed)
+			}
+
+			reporter.report(testCase.event)
+			assert.Equal(t, testCase.elapsed, reported.Elapsed)
+			assert.Equal(t, testCase.eta, reported.ETA)
+		})
+	}
+}
+
+func TestProgressEvent_MarshalJSON(t *testing.T) {
+	event := ProgressEvent{
+		Phase:       ProgressPhaseCountLines,
+		CommitIndex: 3,
+		CommitCount: 12,
+		CommitHash:  "2fa8fa83724e394a098890c40cc324fa90b080b5",
+		CommittedAt: time.Date(2022, 10, 30, 0, 0, 0, 0, time.UTC),
+		FileIndex:   4,
+		FileCount:   8,
+		File:        "main.go",
+		Elapsed:     time.Millisecond * 1500,
+		ETA:         time.Second * 90,
+	}
+
+	serialized, err := json.Marshal(event)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"phase": "countLines",
+		"commitIndex": 3,
+		"commitCount": 12,
+		"commitHash": "2fa8fa83724e394a098890c40cc324fa90b080b5",
+		"committedAt": "2022-10-30T00:00:00Z",
+		"fileIndex": 4,
+		"fileCount": 8,
+		"file": "main.go",
+		"elapsedSeconds": 1.5,
+		"etaSeconds": 90
+	}`, string(serialized))
+}
@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitMaxRetries is the number of retries of a request rejected by a secondary rate limit.
+const RateLimitMaxRetries = 5
+
+const rateLimitInitialBackoff = time.Second * 2
+const rateLimitMaxBackoff = time.Minute * 2
+
+// rateLimitState remembers until when each rate limit resource of the GitHub API is exhausted.
+type rateLimitState struct {
+	mu        sync.Mutex
+	waitUntil map[string]time.Time
+}
+
+func newRateLimitState() *rateLimitState {
+	return &rateLimitState{
+		waitUntil: map[string]time.Time{},
+	}
+}
+
+var gitHubRateLimitState = newRateLimitState()
+
+func (s *rateLimitState) get(resource string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waitUntil[resource]
+}
+
+func (s *rateLimitState) set(resource string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until.After(s.waitUntil[resource]) {
+		s.waitUntil[resource] = until
+	}
+}
+
+// rateLimitTransport waits only when the X-RateLimit-* headers say the limit is exhausted,
+// and retries requests rejected by secondary rate limits with backoff.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	state *rateLimitState
+	now   func() time.Time
+	sleep func(ctx context.Context, duration time.Duration) error
+}
+
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{
+		base:  base,
+		state: gitHubRateLimitState,
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	logger := LoggerFromContext(ctx)
+	resource := rateLimitResource(request)
+	backoff := rateLimitInitialBackoff
+
+	for retry := 0; ; retry++ {
+		if wait := t.state.get(resource).Sub(t.now()); wait > 0 {
+			logger.Debugf("wait for rate limit reset: resource=%v, wait=%v", resource, wait)
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		attempt := request
+		if retry > 0 {
+			var err error
+			attempt, err = rewindRequest(request)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := t.base.RoundTrip(attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		reset, exhausted := rateLimitReset(response)
+		if exhausted {
+			t.state.set(resource, reset)
+		}
+
+		if !isRateLimited(response) || retry >= RateLimitMaxRetries {
+			return response, nil
+		}
+
+		wait := backoff
+		if retryAfter, ok := retryAfterDuration(response); ok {
+			wait = retryAfter
+		} else if exhausted {
+			wait = reset.Sub(t.now())
+		}
+		backoff *= 2
+		if backoff > rateLimitMaxBackoff {
+			backoff = rateLimitMaxBackoff
+		}
+
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+
+		logger.Debugf(
+			"rate limited: resource=%v, status=%v, retry=%v/%v, wait=%v",
+			resource, response.StatusCode, retry+1, RateLimitMaxRetries, wait,
+		)
+		if wait > 0 {
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func rateLimitResource(request *http.Request) string {
+	if strings.HasSuffix(request.URL.Path, "/graphql") {
+		return "graphql"
+	} else if strings.Contains(request.URL.Path, "/search/") {
+		return "search"
+	}
+	return "core"
+}
+
+func rateLimitReset(response *http.Response) (time.Time, bool) {
+	remaining, err := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0), true
+}
+
+func retryAfterDuration(response *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// isRateLimited reports whether the response is rejected by a primary or secondary rate limit.
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+func isRateLimited(response *http.Response) bool {
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if response.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if response.Header.Get("Retry-After") != "" || response.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "rate limit")
+}
+
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	attempt := request.Clone(request.Context())
+	if request.Body != nil && request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt.Body = body
+	}
+	return attempt, nil
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
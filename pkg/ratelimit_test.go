@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRateLimitTransport(now time.Time) (*rateLimitTransport, *[]time.Duration) {
+	sleeps := make([]time.Duration, 0)
+	transport := &rateLimitTransport{
+		base:  http.DefaultTransport,
+		state: newRateLimitState(),
+		now: func() time.Time {
+			return now
+		},
+		sleep: func(ctx context.Context, duration time.Duration) error {
+			sleeps = append(sleeps, duration)
+			return nil
+		},
+	}
+	return transport, &sleeps
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("wait until reset when exhausted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Second*30).Unix(), 10))
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		transport, sleeps := newTestRateLimitTransport(now)
+		client := &http.Client{Transport: transport}
+
+		_, err := client.Get(server.URL + "/search/users")
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Duration{}, *sleeps)
+
+		_, err = client.Get(server.URL + "/search/users")
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Duration{time.Second * 30}, *sleeps)
+
+		_, err = client.Get(server.URL + "/repos/yktakaha4/kunitori")
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Duration{time.Second * 30}, *sleeps)
+	})
+
+	t.Run("retry secondary rate limit", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "query", string(body))
+
+			switch requests {
+			case 1:
+				w.Header().Set("Retry-After", "10")
+				w.WriteHeader(http.StatusForbidden)
+			case 2:
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+			default:
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		defer server.Close()
+
+		transport, sleeps := newTestRateLimitTransport(now)
+		client := &http.Client{Transport: transport}
+
+		response, err := client.Post(server.URL+"/api/graphql", "text/plain", strings.NewReader("query"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, []time.Duration{time.Second * 10, rateLimitInitialBackoff * 2}, *sleeps)
+	})
+
+	t.Run("give up after max retries", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		transport, _ := newTestRateLimitTransport(now)
+		client := &http.Client{Transport: transport}
+
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+		assert.Equal(t, RateLimitMaxRetries+1, requests)
+	})
+
+	t.Run("do not retry other errors", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+		}))
+		defer server.Close()
+
+		transport, sleeps := newTestRateLimitTransport(now)
+		client := &http.Client{Transport: transport}
+
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+		body, err := io.ReadAll(response.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "Resource not accessible")
+		assert.Equal(t, 1, requests)
+		assert.Equal(t, []time.Duration{}, *sleeps)
+	})
+}
+
+func TestSleepContext(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, sleepContext(ctx, time.Hour), context.Canceled)
+	})
+}
@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dlclark/regexp2"
+	"io"
+)
+
+// LoadGenerateResult reads GenerateResult written by the json format.
+// Results without schema version or written by a newer version are rejected.
+func LoadGenerateResult(reader io.Reader) (*GenerateResult, error) {
+	var generateResult GenerateResult
+	err := json.NewDecoder(reader).Decode(&generateResult)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generate result: err=%w", err)
+	}
+
+	if generateResult.SchemaVersion == 0 {
+		return nil, fmt.Errorf("generate result has no schema version: expected=%v", GenerateResultSchemaVersion)
+	}
+	if generateResult.SchemaVersion > GenerateResultSchemaVersion {
+		return nil, fmt.Errorf(
+			"unsupported schema version: schemaVersion=%v, expected=%v",
+			generateResult.SchemaVersion,
+			GenerateResultSchemaVersion,
+		)
+	}
+
+	err = ValidateColors(generateResult.Colors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generate result: err=%w", err)
+	}
+
+	return &generateResult, nil
+}
+
+// Reallocate allocates areas of region again to the authors of generateResult by allocation.
+// Commits are allocated in the same order as Generate does, so the stable allocation gives the same areas.
+func Reallocate(ctx context.Context, generateResult *GenerateResult, region string, allocation string) (*GenerateResult, error) {
+	areaInfo, err := GetAreaInfo(region)
+	if err != nil {
+		return nil, err
+	}
+
+	if allocation == "" {
+		allocation = AllocationGreedy
+	}
+	allocator, err := GetAllocator(allocation)
+	if err != nil {
+		return nil, err
+	}
+
+	reallocated := *generateResult
+	reallocated.Region = areaInfo.Region
+	reallocated.Allocation = allocation
+	reallocated.Commits = make([]GenerateResultCommit, 0, len(generateResult.Commits))
+
+	for _, commit := range generateResult.Commits {
+		lineCounts := make([]GenerateResultCommitLineCount, 0, len(commit.LineCounts))
+		for _, lineCount := range commit.LineCounts {
+			filter, err := regexp2.Compile(lineCount.FilterRegex, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			result := &CountLinesResult{
+				Filter:        filter,
+				LinesByAuthor: map[string]int{},
+				NameByAuthor:  map[string]string{},
+			}
+			if generateResult.Weighting.Method == WeightingRecency {
+				result.ScoreByAuthor = map[string]float64{}
+			}
+
+			authorByEmail := map[string]GenerateResultCommitLineCountAuthor{}
+			for _, author := range lineCount.Authors {
+				authorByEmail[author.Email] = author
+				result.LinesByAuthor[author.Email] = author.LineCount
+				result.NameByAuthor[author.Email] = author.Name
+				if result.ScoreByAuthor != nil {
+					result.ScoreByAuthor[author.Email] = author.Score
+				}
+			}
+
+			newAuthor := func(email string, lineCount int, rank int) GenerateResultCommitLineCountAuthor {
+				author := authorByEmail[email]
+				author.Rank = rank
+				return author
+			}
+
+			areas, authors, err := allocateLineCount(ctx, allocator, areaInfo, result, newAuthor)
+			if err != nil {
+				return nil, err
+			}
+
+			lineCount.Areas = areas
+			lineCount.Authors = authors
+			lineCounts = append(lineCounts, lineCount)
+		}
+
+		commit.LineCounts = lineCounts
+		reallocated.Commits = append(reallocated.Commits, commit)
+	}
+
+	return &reallocated, nil
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestLoadGenerateResult(t *testing.T) {
+	data, err := json.Marshal(newTestExportResult())
+	assert.NoError(t, err)
+
+	generateResult, err := LoadGenerateResult(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, newTestExportResult(), generateResult)
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "no schema version",
+			data: `{"repository":"https://github.com/yktakaha4/kunitori","commits":[]}`,
+		},
+		{
+			name: "newer schema version",
+			data: `{"schemaVersion":999,"repository":"https://github.com/yktakaha4/kunitori","commits":[]}`,
+		},
+		{
+			name: "invalid color",
+			data: `{"schemaVersion":1,"repository":"https://github.com/yktakaha4/kunitori","commits":[],"colors":{"alice@example.com":"#000000\" onload=\"alert(1)"}}`,
+		},
+		{
+			name: "not json",
+			data: `<html></html>`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := LoadGenerateResult(strings.NewReader(testCase.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReallocate(t *testing.T) {
+	generateResult := newTestExportResult()
+
+	reallocated, err := Reallocate(context.Background(), generateResult, "__TEST", AllocationLargestRemainder)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "__TEST", reallocated.Region)
+	assert.Equal(t, AllocationLargestRemainder, reallocated.Allocation)
+	assert.Equal(t, AllocationGreedy, generateResult.Allocation)
+
+	lineCount := reallocated.Commits[0].LineCounts[0]
+	assert.Equal(t, 3, len(lineCount.Areas))
+	totalRatio := float64(0)
+	for _, area := range lineCount.Areas {
+		totalRatio += area.Ratio
+	}
+	assert.InDelta(t, 1, totalRatio, 1e-9)
+
+	assert.Equal(t, 3, len(lineCount.Authors))
+	assert.Equal(t, GenerateResultCommitLineCountAuthor{
+		Email:       "alice@example.com",
+		Name:        "Alice",
+		GitHubLogin: "alice",
+		LineCount:   60,
+		Rank:        1,
+	}, lineCount.Authors[0])
+
+	_, err = Reallocate(context.Background(), generateResult, "XX", AllocationGreedy)
+	assert.Error(t, err)
+	_, err = Reallocate(context.Background(), generateResult, "__TEST", "unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,338 @@
+package pkg
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenerateResultSchemaJson is the JSON Schema of GenerateResult published with the release.
+// It is kept the same as NewGenerateResultSchema by TestGenerateResultSchema.
+//
+//go:embed generate_result.schema.json
+var GenerateResultSchemaJson []byte
+
+const (
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	jsonSchemaId      = "https://github.com/yktakaha4/kunitori/blob/main/pkg/generate_result.schema.json"
+)
+
+// JsonSchemaTypes is the "type" keyword. A single type is written as a string.
+type JsonSchemaTypes []string
+
+func (t JsonSchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *JsonSchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = JsonSchemaTypes{single}
+		return nil
+	}
+	var types []string
+	err := json.Unmarshal(data, &types)
+	if err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// JsonSchema is the subset of JSON Schema needed to describe GenerateResult.
+type JsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Id                   string                 `json:"$id,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 JsonSchemaTypes        `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	AnyOf                []*JsonSchema          `json:"anyOf,omitempty"`
+	Properties           map[string]*JsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JsonSchema            `json:"additionalProperties,omitempty"`
+	Items                *JsonSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Defs                 map[string]*JsonSchema `json:"$defs,omitempty"`
+}
+
+// NewGenerateResultSchema generates the JSON Schema of GenerateResult from its Go type.
+// Fields without omitempty are required, and slices, maps and pointers among them may be null.
+func NewGenerateResultSchema() (*JsonSchema, error) {
+	generator := &jsonSchemaGenerator{
+		defs: map[string]*JsonSchema{},
+	}
+
+	schema, err := generator.structSchema(reflect.TypeOf(GenerateResult{}))
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = jsonSchemaDialect
+	schema.Id = jsonSchemaId
+	schema.Title = "GenerateResult"
+	schema.Properties["schemaVersion"].Const = GenerateResultSchemaVersion
+	schema.Defs = generator.defs
+
+	return schema, nil
+}
+
+// MarshalJsonSchema writes schema in the format of GenerateResultSchemaJson.
+func MarshalJsonSchema(schema *JsonSchema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type jsonSchemaGenerator struct {
+	defs map[string]*JsonSchema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *jsonSchemaGenerator) schemaOf(t reflect.Type) (*JsonSchema, error) {
+	if t == timeType {
+		return &JsonSchema{Type: JsonSchemaTypes{"string"}, Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// registered before the fields so that recursive types refer to themselves
+			g.defs[t.Name()] = &JsonSchema{}
+			schema, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.defs[t.Name()] = schema
+		}
+		return &JsonSchema{Ref: "#/$defs/" + t.Name()}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := &JsonSchema{Type: JsonSchemaTypes{"array"}, Items: items}
+		if t.Kind() == reflect.Array {
+			length := t.Len()
+			schema.MinItems, schema.MaxItems = &length, &length
+		}
+		return schema, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key: type=%v", t)
+		}
+		values, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JsonSchema{Type: JsonSchemaTypes{"object"}, AdditionalProperties: values}, nil
+	case reflect.String:
+		return &JsonSchema{Type: JsonSchemaTypes{"string"}}, nil
+	case reflect.Bool:
+		return &JsonSchema{Type: JsonSchemaTypes{"boolean"}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JsonSchema{Type: JsonSchemaTypes{"integer"}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JsonSchema{Type: JsonSchemaTypes{"number"}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: type=%v", t)
+}
+
+func (g *jsonSchemaGenerator) structSchema(t reflect.Type) (*JsonSchema, error) {
+	schema := &JsonSchema{
+		Type:       JsonSchemaTypes{"object"},
+		Properties: map[string]*JsonSchema{},
+		Required:   make([]string, 0),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := strings.Contains(","+options+",", ",omitempty,")
+
+		property, err := g.schemaOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %w", t.Name(), field.Name, err)
+		}
+
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+
+			switch field.Type.Kind() {
+			case reflect.Slice, reflect.Map, reflect.Pointer:
+				// nil is marshalled as null unless it is omitted
+				if property.Ref != "" {
+					property = &JsonSchema{AnyOf: []*JsonSchema{property, {Type: JsonSchemaTypes{"null"}}}}
+				} else {
+					property.Type = append(property.Type, "null")
+				}
+			}
+		}
+
+		schema.Properties[name] = property
+	}
+
+	return schema, nil
+}
+
+// ValidateGenerateResult checks the json of GenerateResult against GenerateResultSchemaJson.
+// It returns the violations with their json paths, or an error if the json cannot be read.
+func ValidateGenerateResult(reader io.Reader) ([]string, error) {
+	var schema JsonSchema
+	err := json.Unmarshal(GenerateResultSchemaJson, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(reader)
+	err = decoder.Decode(&value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: err=%w", err)
+	}
+
+	violations := make([]string, 0)
+	validateJsonSchema(&schema, &schema, value, "$", &violations)
+
+	return violations, nil
+}
+
+func validateJsonSchema(root *JsonSchema, schema *JsonSchema, value interface{}, path string, violations *[]string) {
+	if schema.Ref != "" {
+		ref, ok := root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%v: unknown reference %v", path, schema.Ref))
+			return
+		}
+		validateJsonSchema(root, ref, value, path, violations)
+		return
+	}
+
+	if len(schema.AnyOf) > 0 {
+		for _, candidate := range schema.AnyOf {
+			candidateViolations := make([]string, 0)
+			validateJsonSchema(root, candidate, value, path, &candidateViolations)
+			if len(candidateViolations) == 0 {
+				return
+			}
+		}
+		*violations = append(*violations, fmt.Sprintf("%v: should match any of the schemas", path))
+		return
+	}
+
+	if len(schema.Type) > 0 && !isJsonSchemaType(value, schema.Type) {
+		*violations = append(*violations, fmt.Sprintf("%v: should be %v, got %v", path, strings.Join(schema.Type, " or "), jsonTypeOf(value)))
+		return
+	}
+
+	if schema.Const != nil {
+		expected, _ := json.Marshal(schema.Const)
+		actual, _ := json.Marshal(value)
+		if !bytes.Equal(expected, actual) {
+			*violations = append(*violations, fmt.Sprintf("%v: should be %s, got %s", path, expected, actual))
+		}
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, typedValue); err != nil {
+				*violations = append(*violations, fmt.Sprintf("%v: should be date-time, got %q", path, typedValue))
+			}
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(typedValue) < *schema.MinItems {
+			*violations = append(*violations, fmt.Sprintf("%v: should have at least %v items, got %v", path, *schema.MinItems, len(typedValue)))
+		}
+		if schema.MaxItems != nil && len(typedValue) > *schema.MaxItems {
+			*violations = append(*violations, fmt.Sprintf("%v: should have at most %v items, got %v", path, *schema.MaxItems, len(typedValue)))
+		}
+		if schema.Items != nil {
+			for i, item := range typedValue {
+				validateJsonSchema(root, schema.Items, item, fmt.Sprintf("%v[%v]", path, i), violations)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := typedValue[name]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%v: should have %v", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(typedValue))
+		for name := range typedValue {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				validateJsonSchema(root, property, typedValue[name], path+"."+name, violations)
+			} else if schema.AdditionalProperties != nil {
+				validateJsonSchema(root, schema.AdditionalProperties, typedValue[name], fmt.Sprintf("%v[%q]", path, name), violations)
+			}
+		}
+	}
+}
+
+func isJsonSchemaType(value interface{}, types JsonSchemaTypes) bool {
+	actual := jsonTypeOf(value)
+	for _, expected := range types {
+		if expected == actual {
+			return true
+		}
+		if expected == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch typedValue := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if typedValue == math.Trunc(typedValue) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
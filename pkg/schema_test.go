@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+// run with KUNITORI_UPDATE_SCHEMA=yes to write generate_result.schema.json after changing GenerateResult
+func TestGenerateResultSchema(t *testing.T) {
+	schema, err := NewGenerateResultSchema()
+	assert.NoError(t, err)
+	data, err := MarshalJsonSchema(schema)
+	assert.NoError(t, err)
+
+	if os.Getenv("KUNITORI_UPDATE_SCHEMA") == "yes" {
+		err := os.WriteFile("generate_result.schema.json", data, 0644)
+		assert.NoError(t, err)
+		return
+	}
+
+	assert.Equal(t, string(data), string(GenerateResultSchemaJson), "generate_result.schema.json is outdated, run make schema")
+
+	assert.Equal(t, float64(GenerateResultSchemaVersion), schemaVersionConstOf(t, GenerateResultSchemaJson))
+	assert.Contains(t, schema.Required, "commits")
+	assert.NotContains(t, schema.Required, "survival")
+	assert.Equal(t, JsonSchemaTypes{"array", "null"}, schema.Properties["commits"].Type)
+	assert.Equal(t, "date-time", schema.Properties["generatedAt"].Format)
+}
+
+func schemaVersionConstOf(t *testing.T, data []byte) interface{} {
+	var schema JsonSchema
+	err := json.Unmarshal(data, &schema)
+	assert.NoError(t, err)
+	return schema.Properties["schemaVersion"].Const
+}
+
+func TestValidateGenerateResult(t *testing.T) {
+	generateResult := newTestExportResult()
+	generateResult.Survival = newGenerateResultSurvival(nil, nil)
+	data, err := json.Marshal(generateResult)
+	assert.NoError(t, err)
+
+	violations, err := ValidateGenerateResult(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	testCases := []struct {
+		name       string
+		data       string
+		violations []string
+	}{
+		{
+			name: "old schema version",
+			data: strings.Replace(string(data), `"schemaVersion":1`, `"schemaVersion":0`, 1),
+			violations: []string{
+				"$.schemaVersion: should be 1, got 0",
+			},
+		},
+		{
+			name: "wrong types",
+			data: strings.Replace(
+				strings.Replace(string(data), `"lineCount":60`, `"lineCount":"60"`, 1),
+				`"committedAt":"2023-01-01T00:00:00Z"`, `"committedAt":"yesterday"`, 1,
+			),
+			violations: []string{
+				`$.commits[0].committedAt: should be date-time, got "yesterday"`,
+				"$.commits[0].lineCounts[0].authors[0].lineCount: should be integer, got string",
+			},
+		},
+		{
+			name: "missing fields",
+			data: `{"schemaVersion":1,"commits":[{"hash":"2fa8fa8"}]}`,
+			violations: []string{
+				"$: should have repository",
+				"$: should have source",
+				"$: should have gitHubUrl",
+				"$: should have region",
+				"$: should have allocation",
+				"$: should have groupBy",
+				"$: should have ownership",
+				"$: should have weighting",
+				"$: should have generatedAt",
+				"$.commits[0]: should have committedAt",
+				"$.commits[0]: should have lineCounts",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			violations, err := ValidateGenerateResult(strings.NewReader(testCase.data))
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.violations, violations)
+		})
+	}
+
+	_, err = ValidateGenerateResult(strings.NewReader("{"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"sort"
+	"time"
+)
+
+// GenerateResultSurvival tells how many lines added in each period are still alive at the later snapshots.
+type GenerateResultSurvival struct {
+	// Periods are the ends of the periods lines are added in, which are the sampled commits in chronological order.
+	// The first period also holds the lines added before it.
+	Periods []time.Time `json:"periods"`
+	// Snapshots are the counted commits in chronological order. They equal to Periods unless the result is partial.
+	Snapshots []time.Time                    `json:"snapshots"`
+	Filters   []GenerateResultSurvivalFilter `json:"filters"`
+}
+
+type GenerateResultSurvivalFilter struct {
+	FilterRegex string                         `json:"filterRegex"`
+	Authors     []GenerateResultSurvivalAuthor `json:"authors"`
+}
+
+type GenerateResultSurvivalAuthor struct {
+	Email string `json:"email"`
+	// Cohorts[i][j] is the number of lines added in Periods[i] and alive at Snapshots[j].
+	Cohorts [][]int `json:"cohorts"`
+}
+
+type survivalSnapshot struct {
+	committedAt time.Time
+	results     []*CountLinesResult
+}
+
+// newGenerateResultSurvival builds survival cohorts from results counted with periods.
+func newGenerateResultSurvival(periods []time.Time, snapshots []survivalSnapshot) *GenerateResultSurvival {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].committedAt.Before(snapshots[j].committedAt)
+	})
+
+	survival := &GenerateResultSurvival{
+		Periods:   periods,
+		Snapshots: make([]time.Time, 0, len(snapshots)),
+		Filters:   make([]GenerateResultSurvivalFilter, 0),
+	}
+	for _, snapshot := range snapshots {
+		survival.Snapshots = append(survival.Snapshots, snapshot.committedAt)
+	}
+	if len(snapshots) == 0 {
+		return survival
+	}
+
+	for filterIndex, filterResult := range snapshots[0].results {
+		cohortsByAuthor := map[string][][]int{}
+		for snapshotIndex, snapshot := range snapshots {
+			result := snapshot.results[filterIndex]
+			for author, linesByPeriod := range result.LinesByPeriod {
+				cohorts, ok := cohortsByAuthor[author]
+				if !ok {
+					cohorts = make([][]int, len(periods))
+					for i := range cohorts {
+						cohorts[i] = make([]int, len(snapshots))
+					}
+					cohortsByAuthor[author] = cohorts
+				}
+				for period, lines := range linesByPeriod {
+					cohorts[period][snapshotIndex] = lines
+				}
+			}
+		}
+
+		authors := make([]GenerateResultSurvivalAuthor, 0, len(cohortsByAuthor))
+		for author, cohorts := range cohortsByAuthor {
+			authors = append(authors, GenerateResultSurvivalAuthor{
+				Email:   author,
+				Cohorts: cohorts,
+			})
+		}
+		sort.SliceStable(authors, func(i, j int) bool {
+			return authors[i].Email < authors[j].Email
+		})
+
+		survival.Filters = append(survival.Filters, GenerateResultSurvivalFilter{
+			FilterRegex: filterResult.Filter.String(),
+			Authors:     authors,
+		})
+	}
+
+	return survival
+}
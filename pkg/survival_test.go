@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"github.com/dlclark/regexp2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewGenerateResultSurvival(t *testing.T) {
+	filter := regexp2.MustCompile("\\.go$", 0)
+	periods := []time.Time{
+		time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	snapshots := []survivalSnapshot{
+		{
+			committedAt: periods[2],
+			results: []*CountLinesResult{
+				{Filter: filter, LinesByPeriod: map[string][]int{
+					"alice@example.com": {4, 2, 3},
+					"bob@example.com":   {0, 0, 5},
+				}},
+			},
+		},
+		{
+			committedAt: periods[1],
+			results: []*CountLinesResult{
+				{Filter: filter, LinesByPeriod: map[string][]int{
+					"alice@example.com": {6, 8, 0},
+				}},
+			},
+		},
+	}
+
+	t.Run("partial", func(t *testing.T) {
+		survival := newGenerateResultSurvival(periods, snapshots)
+		assert.Equal(t, &GenerateResultSurvival{
+			Periods:   periods,
+			Snapshots: []time.Time{periods[1], periods[2]},
+			Filters: []GenerateResultSurvivalFilter{
+				{
+					FilterRegex: "\\.go$",
+					Authors: []GenerateResultSurvivalAuthor{
+						{Email: "alice@example.com", Cohorts: [][]int{{6, 4}, {8, 2}, {0, 3}}},
+						{Email: "bob@example.com", Cohorts: [][]int{{0, 0}, {0, 0}, {0, 5}}},
+					},
+				},
+			},
+		}, survival)
+	})
+
+	t.Run("no snapshot", func(t *testing.T) {
+		survival := newGenerateResultSurvival(periods, []survivalSnapshot{})
+		assert.Equal(t, 0, len(survival.Snapshots))
+		assert.Equal(t, 0, len(survival.Filters))
+	})
+}
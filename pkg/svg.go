@@ -0,0 +1,218 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	svgTileSize   = 40
+	svgTileGap    = 4
+	svgMargin     = 16
+	svgTitleSize  = 24
+	svgLegendSize = 16
+	svgLegendGap  = 32
+)
+
+// svgPalette is the color axis of the chart, from the top ranked author to the lowest.
+var svgPalette = [][3]int{
+	{0xff, 0x00, 0x00},
+	{0xff, 0x80, 0x00},
+	{0xff, 0xff, 0x00},
+	{0x00, 0xff, 0x00},
+	{0x00, 0xff, 0xff},
+	{0x00, 0x00, 0xff},
+	{0x80, 0x00, 0xff},
+}
+
+const svgNoAuthorColor = "#eeeeee"
+
+// RenderChartSvg renders areas of the latest commit as a tile map, one for each filter.
+// Unlike the html chart, it needs no script and can be embedded in READMEs.
+func RenderChartSvg(generateResult *GenerateResult) (string, error) {
+	if generateResult == nil {
+		return "", errors.New("generate result is nil")
+	}
+
+	areaInfo, err := GetAreaInfo(generateResult.Region)
+	if err != nil {
+		return "", err
+	}
+	if len(areaInfo.Tiles) == 0 {
+		return "", fmt.Errorf("region has no tiles: region=%v", areaInfo.Region)
+	}
+
+	columns, rows := 0, 0
+	for _, tile := range areaInfo.Tiles {
+		if tile[0]+1 > columns {
+			columns = tile[0] + 1
+		}
+		if tile[1]+1 > rows {
+			rows = tile[1] + 1
+		}
+	}
+	mapWidth := columns*(svgTileSize+svgTileGap) - svgTileGap
+	mapHeight := rows*(svgTileSize+svgTileGap) - svgTileGap
+
+	maxAuthorRank := 0
+	for _, commit := range generateResult.Commits {
+		for _, lineCount := range commit.LineCounts {
+			for _, area := range lineCount.Areas {
+				if area.AuthorRank > maxAuthorRank {
+					maxAuthorRank = area.AuthorRank
+				}
+			}
+		}
+	}
+
+	var lineCounts []GenerateResultCommitLineCount
+	if len(generateResult.Commits) > 0 {
+		lineCounts = generateResult.Commits[0].LineCounts
+	}
+
+	legendWidth := 320
+	sectionHeight := svgTitleSize + mapHeight + svgMargin
+	for _, lineCount := range lineCounts {
+		if legendHeight := svgTitleSize + len(lineCount.Authors)*svgLegendSize + svgMargin; legendHeight > sectionHeight {
+			sectionHeight = legendHeight
+		}
+	}
+	width := svgMargin*2 + mapWidth + svgLegendGap + legendWidth
+	sections := len(lineCounts)
+	if sections == 0 {
+		sections = 1
+	}
+	height := svgMargin*2 + sections*sectionHeight
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\" viewBox=\"0 0 %v %v\" font-family=\"sans-serif\">\n",
+		width, height, width, height,
+	))
+	builder.WriteString(fmt.Sprintf("<rect width=\"%v\" height=\"%v\" fill=\"#ebf7fe\"/>\n", width, height))
+
+	lang := langOf(generateResult)
+	if len(lineCounts) == 0 {
+		builder.WriteString(fmt.Sprintf(
+			"<text x=\"%v\" y=\"%v\" font-size=\"14\">%v</text>\n",
+			svgMargin, svgMargin+svgTitleSize,
+			html.EscapeString(messagesOf(lang)["noCommits"]),
+		))
+	}
+
+	for index, lineCount := range lineCounts {
+		top := svgMargin + index*sectionHeight
+		builder.WriteString(fmt.Sprintf("<g transform=\"translate(%v,%v)\">\n", svgMargin, top))
+		builder.WriteString(fmt.Sprintf(
+			"<text x=\"0\" y=\"16\" font-size=\"16\" font-weight=\"bold\">%v</text>\n",
+			html.EscapeString(lineCount.FilterRegex),
+		))
+
+		colorByArea := map[string]string{}
+		for _, area := range lineCount.Areas {
+			colorByArea[area.Name] = svgAuthorColor(generateResult, area.AuthorEmail, area.AuthorRank, maxAuthorRank)
+		}
+
+		for _, area := range areaInfo.Areas {
+			tile, ok := areaInfo.Tiles[area.Name]
+			if !ok {
+				continue
+			}
+			areaName := areaInfo.DisplayName(area.Name, lang)
+			x := tile[0] * (svgTileSize + svgTileGap)
+			y := svgTitleSize + tile[1]*(svgTileSize+svgTileGap)
+			builder.WriteString(fmt.Sprintf(
+				"<rect x=\"%v\" y=\"%v\" width=\"%v\" height=\"%v\" rx=\"4\" fill=\"%v\"><title>%v</title></rect>\n",
+				x, y, svgTileSize, svgTileSize,
+				html.EscapeString(svgAreaColor(colorByArea, area.Name)),
+				html.EscapeString(areaName),
+			))
+			builder.WriteString(fmt.Sprintf(
+				"<text x=\"%v\" y=\"%v\" font-size=\"10\" text-anchor=\"middle\">%v</text>\n",
+				x+svgTileSize/2, y+svgTileSize/2+4,
+				html.EscapeString(svgTileLabel(areaName)),
+			))
+		}
+
+		areaCounts := map[string]int{}
+		for _, area := range lineCount.Areas {
+			areaCounts[area.AuthorEmail]++
+		}
+
+		legendLeft := mapWidth + svgLegendGap
+		for authorIndex, author := range lineCount.Authors {
+			y := svgTitleSize + authorIndex*svgLegendSize
+			color := svgNoAuthorColor
+			if areaCounts[author.Email] > 0 {
+				color = svgAuthorColor(generateResult, author.Email, author.Rank, maxAuthorRank)
+			}
+			builder.WriteString(fmt.Sprintf(
+				"<rect x=\"%v\" y=\"%v\" width=\"12\" height=\"12\" fill=\"%v\"/>\n",
+				legendLeft, y, html.EscapeString(color),
+			))
+			builder.WriteString(fmt.Sprintf(
+				"<text x=\"%v\" y=\"%v\" font-size=\"12\">%v. %v (%v)</text>\n",
+				legendLeft+18, y+11,
+				author.Rank,
+				html.EscapeString(markdownAuthorName(author)),
+				areaCounts[author.Email],
+			))
+		}
+
+		builder.WriteString("</g>\n")
+	}
+
+	builder.WriteString("</svg>\n")
+
+	return builder.String(), nil
+}
+
+// svgAuthorColor returns the colour of the author if authors are coloured, otherwise the colour of the rank.
+func svgAuthorColor(generateResult *GenerateResult, email string, rank int, maxRank int) string {
+	if color, ok := generateResult.Colors[email]; ok {
+		return color
+	}
+	return svgRankColor(rank, maxRank)
+}
+
+func svgAreaColor(colorByArea map[string]string, area string) string {
+	if color, ok := colorByArea[area]; ok {
+		return color
+	}
+	return svgNoAuthorColor
+}
+
+// svgRankColor interpolates the palette as the color axis of the html chart does.
+func svgRankColor(rank int, maxRank int) string {
+	if rank <= 0 {
+		return svgNoAuthorColor
+	}
+
+	position := float64(0)
+	if maxRank > 1 {
+		position = float64(rank-1) / float64(maxRank-1) * float64(len(svgPalette)-1)
+	}
+	lower := int(position)
+	if lower >= len(svgPalette)-1 {
+		lower = len(svgPalette) - 2
+	}
+	ratio := position - float64(lower)
+
+	var color [3]int
+	for i := range color {
+		color[i] = int(float64(svgPalette[lower][i])*(1-ratio) + float64(svgPalette[lower+1][i])*ratio + 0.5)
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", color[0], color[1], color[2])
+}
+
+// svgTileLabel shortens the area name to fit in a tile.
+func svgTileLabel(name string) string {
+	runes := []rune(name)
+	if len(runes) > 4 {
+		return string(runes[:4])
+	}
+	return name
+}
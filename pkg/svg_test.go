@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRenderChartSvg(t *testing.T) {
+	svg, err := RenderChartSvg(newTestExportResult())
+	assert.NoError(t, err)
+
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+
+	assert.Contains(t, svg, `fill="#ff0000"><title>Area30</title>`)
+	assert.Contains(t, svg, `fill="#8000ff"><title>Area20</title>`)
+	assert.Contains(t, svg, `fill="#eeeeee"><title>Area10</title>`)
+	assert.Contains(t, svg, "1. @alice (1)")
+	assert.Contains(t, svg, "3. carol@example.com (0)")
+
+	generateResult := newTestExportResult()
+	generateResult.Colors = map[string]string{"alice@example.com": "#123456", "bob@example.com": "#abcdef"}
+	svg, err = RenderChartSvg(generateResult)
+	assert.NoError(t, err)
+	assert.Contains(t, svg, `fill="#123456"><title>Area30</title>`)
+	assert.Contains(t, svg, `fill="#abcdef"><title>Area20</title>`)
+	assert.Contains(t, svg, `fill="#eeeeee"><title>Area10</title>`)
+
+	generateResult.Lang = LangJa
+	svg, err = RenderChartSvg(generateResult)
+	assert.NoError(t, err)
+	assert.Contains(t, svg, `fill="#123456"><title>エリア30</title>`)
+	assert.Contains(t, svg, `>エリア3</text>`)
+
+	generateResult.Commits = nil
+	svg, err = RenderChartSvg(generateResult)
+	assert.NoError(t, err)
+	assert.Contains(t, svg, "コミットが見つかりません。")
+
+	generateResult.Region = "XX"
+	_, err = RenderChartSvg(generateResult)
+	assert.Error(t, err)
+}
+
+func TestSvgRankColor(t *testing.T) {
+	assert.Equal(t, "#eeeeee", svgRankColor(0, 7))
+	assert.Equal(t, "#ff0000", svgRankColor(1, 7))
+	assert.Equal(t, "#ffff00", svgRankColor(3, 7))
+	assert.Equal(t, "#8000ff", svgRankColor(7, 7))
+	assert.Equal(t, "#ff4000", svgRankColor(2, 13))
+	assert.Equal(t, "#ff0000", svgRankColor(1, 1))
+}
@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	GroupByAuthor = "author"
+	GroupByTeam   = "team"
+)
+
+// TeamUnassigned is the team of authors who are not a member of any team.
+const TeamUnassigned = "(no team)"
+
+// Teams maps a team name to its members, which are author emails or labels given by AuthorRegex.
+type Teams map[string][]string
+
+// LoadTeams reads a JSON object of team names to member lists, e.g. {"backend": ["alice@example.com"]}.
+func LoadTeams(path string) (Teams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := Teams{}
+	err = json.Unmarshal(data, &teams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid teams: path=%v, err=%w", path, err)
+	}
+
+	_, err = teams.teamByMember()
+	if err != nil {
+		return nil, fmt.Errorf("invalid teams: path=%v, err=%w", path, err)
+	}
+
+	return teams, nil
+}
+
+func (t Teams) teamByMember() (map[string]string, error) {
+	teamByMember := map[string]string{}
+	for team, members := range t {
+		if team == TeamUnassigned {
+			return nil, fmt.Errorf("reserved team name: team=%v", team)
+		}
+		for _, member := range members {
+			key := strings.ToLower(member)
+			if other, ok := teamByMember[key]; ok {
+				return nil, fmt.Errorf("member belongs to multiple teams: member=%v, teams=%v,%v", member, other, team)
+			}
+			teamByMember[key] = team
+		}
+	}
+	return teamByMember, nil
+}
+
+// GroupLinesByTeam aggregates lines of authors into their teams.
+// Authors of each team are kept in MembersByAuthor of the returned result.
+func GroupLinesByTeam(result *CountLinesResult, teams Teams) (*CountLinesResult, error) {
+	teamByMember, err := teams.teamByMember()
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := &CountLinesResult{
+		Filter:          result.Filter,
+		LinesByAuthor:   map[string]int{},
+		NameByAuthor:    map[string]string{},
+		HashByAuthor:    map[string]string{},
+		MatchedFiles:    result.MatchedFiles,
+		MembersByAuthor: map[string][]string{},
+	}
+	if result.ScoreByAuthor != nil {
+		grouped.ScoreByAuthor = map[string]float64{}
+	}
+	if result.LinesByPeriod != nil {
+		grouped.LinesByPeriod = map[string][]int{}
+	}
+	if result.LinesByFile != nil {
+		grouped.LinesByFile = map[string]map[string]int{}
+		for file, linesByAuthor := range result.LinesByFile {
+			grouped.LinesByFile[file] = map[string]int{}
+			for author, lines := range linesByAuthor {
+				team, ok := teamByMember[strings.ToLower(author)]
+				if !ok {
+					team = TeamUnassigned
+				}
+				grouped.LinesByFile[file][team] += lines
+			}
+		}
+	}
+
+	authors := make([]string, 0)
+	for author := range result.LinesByAuthor {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	for _, author := range authors {
+		team, ok := teamByMember[strings.ToLower(author)]
+		if !ok {
+			team = TeamUnassigned
+		}
+
+		grouped.LinesByAuthor[team] += result.LinesByAuthor[author]
+		if grouped.ScoreByAuthor != nil {
+			grouped.ScoreByAuthor[team] += result.ScoreByAuthor[author]
+		}
+		if grouped.LinesByPeriod != nil {
+			for period, lines := range result.LinesByPeriod[author] {
+				if grouped.LinesByPeriod[team] == nil {
+					grouped.LinesByPeriod[team] = make([]int, len(result.LinesByPeriod[author]))
+				}
+				grouped.LinesByPeriod[team][period] += lines
+			}
+		}
+		grouped.NameByAuthor[team] = team
+		grouped.MembersByAuthor[team] = append(grouped.MembersByAuthor[team], author)
+	}
+
+	return grouped, nil
+}
+
+func newMemberAuthors(result *CountLinesResult, members []string, login func(email string) string) []GenerateResultCommitLineCountAuthor {
+	authors := make([]GenerateResultCommitLineCountAuthor, 0, len(members))
+	for _, member := range members {
+		authors = append(authors, GenerateResultCommitLineCountAuthor{
+			Email:       member,
+			Name:        result.NameByAuthor[member],
+			GitHubLogin: login(member),
+			LineCount:   result.LinesByAuthor[member],
+			Score:       result.ScoreByAuthor[member],
+		})
+	}
+
+	sortAuthorsByWeight(authors)
+
+	for i := range authors {
+		authors[i].Rank = i + 1
+	}
+
+	return authors
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"github.com/dlclark/regexp2"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTeams(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("valid", func(t *testing.T) {
+		path := filepath.Join(dir, "teams.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"backend":["alice@example.com","bob@example.com"],"frontend":["carol@example.com"]}`), 0644))
+
+		teams, err := LoadTeams(path)
+		assert.NoError(t, err)
+		assert.Equal(t, Teams{
+			"backend":  {"alice@example.com", "bob@example.com"},
+			"frontend": {"carol@example.com"},
+		}, teams)
+	})
+
+	t.Run("member belongs to multiple teams", func(t *testing.T) {
+		path := filepath.Join(dir, "duplicated.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"backend":["alice@example.com"],"frontend":["Alice@example.com"]}`), 0644))
+
+		_, err := LoadTeams(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("reserved team name", func(t *testing.T) {
+		path := filepath.Join(dir, "reserved.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"(no team)":["alice@example.com"]}`), 0644))
+
+		_, err := LoadTeams(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := LoadTeams(filepath.Join(dir, "missing.json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestGroupLinesByTeam(t *testing.T) {
+	filter := regexp2.MustCompile("\\.go$", 0)
+	result := &CountLinesResult{
+		Filter: filter,
+		LinesByAuthor: map[string]int{
+			"alice@example.com": 10,
+			"BOB@example.com":   20,
+			"carol@example.com": 5,
+			"dave@example.com":  1,
+		},
+		NameByAuthor: map[string]string{
+			"alice@example.com": "Alice",
+			"BOB@example.com":   "Bob",
+			"carol@example.com": "Carol",
+			"dave@example.com":  "Dave",
+		},
+		HashByAuthor: map[string]string{},
+		MatchedFiles: []string{"main.go"},
+		LinesByFile: map[string]map[string]int{
+			"main.go": {"alice@example.com": 10, "BOB@example.com": 20, "carol@example.com": 5, "dave@example.com": 1},
+		},
+	}
+	teams := Teams{
+		"backend":  {"alice@example.com", "bob@example.com"},
+		"frontend": {"carol@example.com"},
+	}
+
+	grouped, err := GroupLinesByTeam(result, teams)
+	assert.NoError(t, err)
+	assert.Equal(t, filter, grouped.Filter)
+	assert.Equal(t, []string{"main.go"}, grouped.MatchedFiles)
+	assert.Equal(t, map[string]int{"backend": 30, "frontend": 5, TeamUnassigned: 1}, grouped.LinesByAuthor)
+	assert.Equal(t, map[string]string{"backend": "backend", "frontend": "frontend", TeamUnassigned: TeamUnassigned}, grouped.NameByAuthor)
+	assert.Equal(t, map[string][]string{
+		"backend":      {"BOB@example.com", "alice@example.com"},
+		"frontend":     {"carol@example.com"},
+		TeamUnassigned: {"dave@example.com"},
+	}, grouped.MembersByAuthor)
+	assert.Equal(t, map[string]map[string]int{
+		"main.go": {"backend": 30, "frontend": 5, TeamUnassigned: 1},
+	}, grouped.LinesByFile)
+
+	members := newMemberAuthors(result, grouped.MembersByAuthor["backend"], func(email string) string {
+		return map[string]string{"alice@example.com": "alice"}[email]
+	})
+	assert.Equal(t, []GenerateResultCommitLineCountAuthor{
+		{Email: "BOB@example.com", Name: "Bob", LineCount: 20, Rank: 1},
+		{Email: "alice@example.com", Name: "Alice", GitHubLogin: "alice", LineCount: 10, Rank: 2},
+	}, members)
+}